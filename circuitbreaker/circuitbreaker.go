@@ -0,0 +1,134 @@
+package circuitbreaker
+
+import (
+	"context"
+	"sync"
+
+	"github.com/afex/hystrix-go/hystrix"
+	"github.com/callicoder/go-commons/errors"
+	"github.com/callicoder/go-commons/errors/codes"
+)
+
+// CommandConfig tunes the circuit of a command. Zero values fall back to the defaults.
+type CommandConfig struct {
+	// TimeoutMs is how long to wait for the command to complete
+	TimeoutMs int `mapstructure:"timeout_ms"`
+	// MaxConcurrentRequests is how many calls of the command can run at the same time
+	MaxConcurrentRequests int `mapstructure:"max_concurrent_requests"`
+	// RequestVolumeThreshold is the minimum number of calls before the circuit can open
+	RequestVolumeThreshold int `mapstructure:"request_volume_threshold"`
+	// ErrorPercentThreshold opens the circuit once the rolling error rate exceeds it
+	ErrorPercentThreshold int `mapstructure:"error_percent_threshold"`
+	// SleepWindowMs is how long to wait after the circuit opens before testing for recovery
+	SleepWindowMs int `mapstructure:"sleep_window_ms"`
+}
+
+type Config struct {
+	// Default applies to every command without a specific configuration
+	Default  CommandConfig
+	Commands map[string]CommandConfig
+}
+
+// ErrConfigured is returned by Configure when it was already called.
+var ErrConfigured = errors.New("circuit breaker already configured")
+
+var configureOnce sync.Once
+
+// Configure applies the default and per command settings. It must be called once at
+// startup, before any command runs, as hystrix reads the defaults without synchronization.
+// Later calls return ErrConfigured and change nothing, use ConfigureCommand instead.
+func Configure(c Config) error {
+	err := ErrConfigured
+	configureOnce.Do(func() {
+		configure(c)
+		err = nil
+	})
+	return err
+}
+
+func configure(c Config) {
+	if c.Default.TimeoutMs > 0 {
+		hystrix.DefaultTimeout = c.Default.TimeoutMs
+	}
+	if c.Default.MaxConcurrentRequests > 0 {
+		hystrix.DefaultMaxConcurrent = c.Default.MaxConcurrentRequests
+	}
+	if c.Default.RequestVolumeThreshold > 0 {
+		hystrix.DefaultVolumeThreshold = c.Default.RequestVolumeThreshold
+	}
+	if c.Default.ErrorPercentThreshold > 0 {
+		hystrix.DefaultErrorPercentThreshold = c.Default.ErrorPercentThreshold
+	}
+	if c.Default.SleepWindowMs > 0 {
+		hystrix.DefaultSleepWindow = c.Default.SleepWindowMs
+	}
+
+	for name, conf := range c.Commands {
+		ConfigureCommand(name, conf)
+	}
+}
+
+// ConfigureCommand applies c to the command name, zero values falling back to the defaults.
+func ConfigureCommand(name string, c CommandConfig) {
+	hystrix.ConfigureCommand(name, hystrix.CommandConfig{
+		Timeout:                c.TimeoutMs,
+		MaxConcurrentRequests:  c.MaxConcurrentRequests,
+		RequestVolumeThreshold: c.RequestVolumeThreshold,
+		ErrorPercentThreshold:  c.ErrorPercentThreshold,
+		SleepWindow:            c.SleepWindowMs,
+	})
+}
+
+// Configured reports whether the command name has settings, either set with Configure or
+// ConfigureCommand, or defaulted when the command first ran.
+func Configured(name string) bool {
+	_, ok := hystrix.GetCircuitSettings()[name]
+	return ok
+}
+
+// Do runs fn through the circuit of the command name, blocking until it completes.
+// When fn fails, times out or is rejected by the circuit, fallback is called with the
+// error if it is not nil. Breaker errors are returned as BaseErrors with the
+// codes.CircuitOpen, codes.Timeout or codes.TooManyRequests codes, while context
+// errors are returned as is.
+func Do(ctx context.Context, name string, fn func(ctx context.Context) error, fallback func(ctx context.Context, err error) error) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	var fallbackC func(context.Context, error) error
+	if fallback != nil {
+		fallbackC = func(ctx context.Context, err error) error {
+			return fallback(ctx, mapError(name, err))
+		}
+	}
+
+	return mapError(name, hystrix.DoC(ctx, name, fn, fallbackC))
+}
+
+// IsOpen reports whether err was returned because the circuit was open.
+func IsOpen(err error) bool {
+	return errors.Cause(err) == hystrix.ErrCircuitOpen
+}
+
+// IsTimeout reports whether err was returned because the command timed out.
+func IsTimeout(err error) bool {
+	return errors.Cause(err) == hystrix.ErrTimeout
+}
+
+// IsRejected reports whether err was returned because too many calls of the command were running.
+func IsRejected(err error) bool {
+	return errors.Cause(err) == hystrix.ErrMaxConcurrency
+}
+
+func mapError(name string, err error) error {
+	switch err {
+	case hystrix.ErrCircuitOpen:
+		return errors.WithCode(codes.CircuitOpen).Wrapf(err, "circuit %s is open", name)
+	case hystrix.ErrTimeout:
+		return errors.WithCode(codes.Timeout).Wrapf(err, "command %s timed out", name)
+	case hystrix.ErrMaxConcurrency:
+		return errors.WithCode(codes.TooManyRequests).Wrapf(err, "too many concurrent calls of command %s", name)
+	}
+	return err
+}
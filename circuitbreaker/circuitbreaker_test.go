@@ -0,0 +1,87 @@
+package circuitbreaker
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/afex/hystrix-go/hystrix"
+	commonerrors "github.com/callicoder/go-commons/errors"
+	"github.com/callicoder/go-commons/errors/codes"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDo(t *testing.T) {
+	t.Run("should return the error of the command", func(t *testing.T) {
+		errFailed := errors.New("failed")
+		err := Do(context.Background(), "test_error", func(ctx context.Context) error {
+			return errFailed
+		}, nil)
+
+		assert.Equal(t, errFailed, err)
+	})
+
+	t.Run("should map timeouts to a BaseError", func(t *testing.T) {
+		ConfigureCommand("test_timeout", CommandConfig{TimeoutMs: 10})
+
+		err := Do(context.Background(), "test_timeout", func(ctx context.Context) error {
+			time.Sleep(100 * time.Millisecond)
+			return nil
+		}, nil)
+
+		assert.True(t, IsTimeout(err))
+		baseErr, ok := err.(*commonerrors.BaseErrorStack)
+		assert.True(t, ok)
+		assert.Equal(t, codes.Timeout, baseErr.Code)
+	})
+
+	t.Run("should open the circuit and call the fallback", func(t *testing.T) {
+		ConfigureCommand("test_open", CommandConfig{RequestVolumeThreshold: 1, ErrorPercentThreshold: 1, SleepWindowMs: 60000})
+		fail := func(ctx context.Context) error {
+			return errors.New("failed")
+		}
+
+		for i := 0; i < 5; i++ {
+			_ = Do(context.Background(), "test_open", fail, nil)
+		}
+		// Metrics are collected asynchronously
+		time.Sleep(100 * time.Millisecond)
+
+		var fallbackErr error
+		err := Do(context.Background(), "test_open", fail, func(ctx context.Context, err error) error {
+			fallbackErr = err
+			return nil
+		})
+
+		assert.NoError(t, err)
+		assert.True(t, IsOpen(fallbackErr))
+	})
+
+	t.Run("should return context errors as is", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		err := Do(ctx, "test_cancel", func(ctx context.Context) error {
+			time.Sleep(100 * time.Millisecond)
+			return nil
+		}, nil)
+
+		assert.Equal(t, context.DeadlineExceeded, err)
+	})
+}
+
+func TestConfigure(t *testing.T) {
+	configureOnce = sync.Once{}
+
+	err := Configure(Config{Commands: map[string]CommandConfig{"test_configure": {TimeoutMs: 42}}})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 42*time.Millisecond, hystrix.GetCircuitSettings()["test_configure"].Timeout)
+
+	err = Configure(Config{Commands: map[string]CommandConfig{"test_configure_again": {TimeoutMs: 42}}})
+
+	assert.Equal(t, ErrConfigured, err)
+	assert.NotContains(t, hystrix.GetCircuitSettings(), "test_configure_again")
+}
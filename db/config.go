@@ -3,6 +3,8 @@ package db
 import (
 	"bytes"
 	"strconv"
+
+	"github.com/callicoder/go-commons/retry"
 )
 
 type Config struct {
@@ -15,6 +17,32 @@ type Config struct {
 	Query              string
 	MaxIdleConnections int `mapstructure:"max_idle_connections"`
 	MaxOpenConnections int `mapstructure:"max_open_connections"`
+	// ConnMaxLifetimeMs closes connections older than it. Zero means no limit.
+	ConnMaxLifetimeMs int `mapstructure:"conn_max_lifetime_ms"`
+	// ConnMaxIdleTimeMs closes connections idle for longer than it. Zero means no limit.
+	ConnMaxIdleTimeMs int `mapstructure:"conn_max_idle_time_ms"`
+	// ConnectTimeoutMs bounds every startup ping attempt, defaults to 5000
+	ConnectTimeoutMs int `mapstructure:"connect_timeout_ms"`
+	// ConnectRetry configures the retries of the startup ping
+	ConnectRetry retry.Config `mapstructure:"connect_retry"`
+	// Replicas are read replicas serving SelectContext and GetContext with a ReadOnly context
+	Replicas []ReplicaConfig
+	// ReplicaHealthCheckIntervalMs is the interval between replica pings, defaults to 5000
+	ReplicaHealthCheckIntervalMs int `mapstructure:"replica_health_check_interval_ms"`
+}
+
+// ReplicaConfig is the address of a read replica, which shares the other settings of the
+// primary database.
+type ReplicaConfig struct {
+	Host string
+	Port int
+}
+
+func (cfg Config) replica(replicaConfig ReplicaConfig) Config {
+	cfg.Host = replicaConfig.Host
+	cfg.Port = replicaConfig.Port
+	cfg.Replicas = nil
+	return cfg
 }
 
 func (cfg Config) URL() string {
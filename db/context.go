@@ -0,0 +1,50 @@
+package db
+
+import "context"
+
+type contextKey int
+
+const (
+	txKey contextKey = iota
+	primaryKey
+	readOnlyKey
+)
+
+// NewTxContext returns a copy of ctx which carries tx. Queries run through the SqlDB
+// that began tx with the returned context are routed through tx.
+func NewTxContext(ctx context.Context, tx *SqlTx) context.Context {
+	return context.WithValue(ctx, txKey, tx)
+}
+
+// TxFromContext returns the transaction stored in ctx, if any.
+func TxFromContext(ctx context.Context) (*SqlTx, bool) {
+	if ctx == nil {
+		return nil, false
+	}
+	tx, ok := ctx.Value(txKey).(*SqlTx)
+	return tx, ok && tx != nil
+}
+
+// ReadOnly returns a copy of ctx which lets SelectContext and GetContext run their
+// SELECT queries on the replicas, when the caller knows they have no side effects, e.g.
+// they call no nextval or lock function, and can read slightly stale data. Other queries
+// and SELECTs with a locking clause such as FOR UPDATE always run on the primary.
+func ReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, readOnlyKey, true)
+}
+
+// ForcePrimary returns a copy of ctx which routes reads to the primary database, even
+// when ctx was made ReadOnly, e.g. to read rows written just before.
+func ForcePrimary(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryKey, true)
+}
+
+// readsFromReplica reports whether the queries run with ctx may run on a replica.
+func readsFromReplica(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	readOnly, _ := ctx.Value(readOnlyKey).(bool)
+	forced, _ := ctx.Value(primaryKey).(bool)
+	return readOnly && !forced
+}
@@ -0,0 +1,182 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	commonerrors "github.com/callicoder/go-commons/errors"
+	"github.com/callicoder/go-commons/errors/codes"
+	"github.com/callicoder/go-commons/logger"
+	"github.com/callicoder/go-commons/requestutil"
+	"github.com/callicoder/go-commons/retry"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newMockConfig registers a sqlmock connection under the URL of the returned config, so
+// that New connects to it.
+func newMockConfig(t *testing.T, name string) (Config, sqlmock.Sqlmock) {
+	conf := Config{Driver: "sqlmock", Name: name, Host: "db1", Port: 5432}
+	db, mock, err := sqlmock.NewWithDSN(conf.URL(), sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return conf, mock
+}
+
+// TestNew runs in a new process, as New must not require the root logger, which other
+// tests set up.
+func TestNew(t *testing.T) {
+	if os.Getenv("TEST_WITHOUT_ROOT_LOGGER") == "" {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestNew$", "-test.count=1", "-test.v")
+		cmd.Env = append(os.Environ(), "TEST_WITHOUT_ROOT_LOGGER=1")
+		out, err := cmd.CombinedOutput()
+
+		require.NoError(t, err, string(out))
+		return
+	}
+	require.False(t, logger.Initialized())
+
+	t.Run("should apply pool settings and ping the database", func(t *testing.T) {
+		conf, mock := newMockConfig(t, "pool")
+		conf.MaxOpenConnections = 7
+		conf.MaxIdleConnections = 3
+		conf.ConnMaxLifetimeMs = 60000
+		mock.ExpectPing()
+
+		store, err := New(conf)
+
+		require.NoError(t, err)
+		defer store.Close()
+		assert.Equal(t, 7, store.db.Stats().MaxOpenConnections)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should retry the startup ping", func(t *testing.T) {
+		conf, mock := newMockConfig(t, "retry")
+		conf.ConnectRetry = retry.Config{MaxAttempts: 3, Policy: retry.PolicyConstant, BaseDelayMs: 1}
+		mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+		mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+		mock.ExpectPing()
+
+		store, err := New(conf)
+
+		require.NoError(t, err)
+		defer store.Close()
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should start with an unhealthy replica", func(t *testing.T) {
+		conf, mock := newMockConfig(t, "replicated")
+		replicaConf := conf.replica(ReplicaConfig{Host: "db2", Port: 5432})
+		replicaDB, replicaMock, err := sqlmock.NewWithDSN(replicaConf.URL(), sqlmock.MonitorPingsOption(true))
+		require.NoError(t, err)
+		t.Cleanup(func() { replicaDB.Close() })
+		conf.Replicas = []ReplicaConfig{{Host: "db2", Port: 5432}}
+		mock.ExpectPing()
+		replicaMock.ExpectPing().WillReturnError(errors.New("connection refused"))
+
+		store, err := New(conf)
+
+		require.NoError(t, err)
+		defer store.Close()
+		require.Len(t, store.replicas, 1)
+		assert.False(t, store.replicas[0].isHealthy())
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, replicaMock.ExpectationsWereMet())
+	})
+
+	t.Run("should return an unavailable error naming the database", func(t *testing.T) {
+		conf, mock := newMockConfig(t, "down")
+		conf.ConnectRetry = retry.Config{MaxAttempts: 2, Policy: retry.PolicyConstant, BaseDelayMs: 1}
+		mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+		mock.ExpectPing().WillReturnError(errors.New("connection refused"))
+
+		_, err := New(conf)
+
+		require.Error(t, err)
+		baseErr, ok := err.(*commonerrors.BaseErrorStack)
+		require.True(t, ok, fmt.Sprintf("%T", err))
+		assert.Equal(t, codes.Unavailable, baseErr.Code)
+		assert.Equal(t, "failed to connect to database down on db1:5432", baseErr.Message)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+// recordingLogger keeps the messages logged with Warnf, Errorf and Infof along with the
+// fields of the logger.
+type recordingLogger struct {
+	logger.Logger
+	fields  logger.Fields
+	entries *[]logger.Fields
+}
+
+func newRecordingLogger() *recordingLogger {
+	return &recordingLogger{fields: logger.Fields{}, entries: &[]logger.Fields{}}
+}
+
+func (l *recordingLogger) record(format string, args ...interface{}) {
+	entry := logger.Fields{"msg": fmt.Sprintf(format, args...)}
+	for k, v := range l.fields {
+		entry[k] = v
+	}
+	*l.entries = append(*l.entries, entry)
+}
+
+func (l *recordingLogger) Infof(format string, args ...interface{})  { l.record(format, args...) }
+func (l *recordingLogger) Warnf(format string, args ...interface{})  { l.record(format, args...) }
+func (l *recordingLogger) Errorf(format string, args ...interface{}) { l.record(format, args...) }
+
+func (l *recordingLogger) WithFields(fields logger.Fields) logger.Logger {
+	merged := logger.Fields{}
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &recordingLogger{fields: merged, entries: l.entries}
+}
+
+func TestQueryLogs(t *testing.T) {
+	l := newRecordingLogger()
+	ctx := logger.NewContext(requestutil.WithRequestID(context.Background(), "abc-123"), l)
+
+	t.Run("should log failed queries with the request id", func(t *testing.T) {
+		store, mock := newMockDB(t, "postgres")
+		mock.ExpectExec("UPDATE users").WillReturnError(errors.New("deadlock detected"))
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT name").WillReturnError(errors.New("connection reset"))
+		mock.ExpectRollback()
+
+		_, err := store.ExecContext(ctx, "UPDATE users SET name = $1", "sachin")
+		assert.Error(t, err)
+		err = WithTx(ctx, store, nil, func(ctx context.Context, tx SqlStore) error {
+			var name string
+			return store.GetContext(ctx, &name, "SELECT name FROM users")
+		})
+		assert.Error(t, err)
+
+		assert.Equal(t, []logger.Fields{
+			{"msg": "Database query failed: deadlock detected", "query": "UPDATE users SET name = $1", logger.FieldRequestID: "abc-123"},
+			{"msg": "Database query failed: connection reset", "query": "SELECT name FROM users", logger.FieldRequestID: "abc-123"},
+		}, *l.entries)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should not log missing rows", func(t *testing.T) {
+		*l.entries = nil
+		store, mock := newMockDB(t, "postgres")
+		mock.ExpectQuery("SELECT name").WillReturnRows(sqlmock.NewRows([]string{"name"}))
+
+		var name string
+		assert.Equal(t, sql.ErrNoRows, store.GetContext(ctx, &name, "SELECT name FROM users"))
+
+		assert.Empty(t, *l.entries)
+	})
+}
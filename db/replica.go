@@ -0,0 +1,123 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/callicoder/go-commons/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+const defaultReplicaHealthCheckIntervalMs = 5000
+
+type replica struct {
+	db   *sqlx.DB
+	addr string
+	// healthy is 1 when the last ping succeeded
+	healthy int32
+}
+
+func openReplica(dbConfig Config) (*replica, error) {
+	db, err := open(dbConfig)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &replica{db: db, addr: fmt.Sprintf("%s:%d", dbConfig.Host, dbConfig.Port)}
+	// An unreachable replica does not prevent startup, reads go to the primary until
+	// it passes a health check
+	if err := pingOnce(context.Background(), db, dbConfig); err != nil {
+		logger.FromContextOrStd(context.Background()).Warnf("Database replica %s is unhealthy: %v", r.addr, err)
+	} else {
+		r.healthy = 1
+	}
+	return r, nil
+}
+
+func (r *replica) isHealthy() bool {
+	return atomic.LoadInt32(&r.healthy) == 1
+}
+
+// setHealthy records the result of a health check, logging health changes.
+func (r *replica) setHealthy(err error) {
+	var value int32
+	if err == nil {
+		value = 1
+	}
+	if atomic.SwapInt32(&r.healthy, value) == value {
+		return
+	}
+
+	l := logger.FromContextOrStd(context.Background())
+	if err != nil {
+		l.Warnf("Database replica %s failed its health check: %v", r.addr, err)
+	} else {
+		l.Infof("Database replica %s is healthy again", r.addr)
+	}
+}
+
+// reader returns the replica to run query on, in round-robin order among the healthy
+// replicas, or the primary unless ctx is ReadOnly and query is a SELECT without a locking
+// clause, or when no replica is healthy.
+func (s *SqlDB) reader(ctx context.Context, query string) *sqlx.DB {
+	if len(s.replicas) == 0 || !readsFromReplica(ctx) || !isSelect(query) || lockingClause.MatchString(query) {
+		return s.db
+	}
+
+	next := atomic.AddUint32(&s.next, 1)
+	for i := range s.replicas {
+		r := s.replicas[(int(next)+i)%len(s.replicas)]
+		if r.isHealthy() {
+			return r.db
+		}
+	}
+	return s.db
+}
+
+// lockingClause matches the row locking clauses of postgres and mysql, which can not run
+// on a read-only replica.
+var lockingClause = regexp.MustCompile(`(?i)\bFOR\s+(UPDATE|SHARE|NO\s+KEY\s+UPDATE|KEY\s+SHARE)\b|\bLOCK\s+IN\s+SHARE\s+MODE\b`)
+
+// isSelect reports whether query starts with SELECT. Other statements returning rows,
+// e.g. INSERT ... RETURNING, or a WITH which may modify data, must run on the primary.
+func isSelect(query string) bool {
+	query = strings.TrimSpace(query)
+	return len(query) >= len("SELECT") && strings.EqualFold(query[:len("SELECT")], "SELECT")
+}
+
+func (s *SqlDB) startHealthChecks(dbConfig Config) {
+	if len(s.replicas) == 0 {
+		return
+	}
+
+	intervalMs := dbConfig.ReplicaHealthCheckIntervalMs
+	if intervalMs <= 0 {
+		intervalMs = defaultReplicaHealthCheckIntervalMs
+	}
+
+	s.stop = make(chan struct{})
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		ticker := time.NewTicker(time.Duration(intervalMs) * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				s.checkReplicas(dbConfig)
+			case <-s.stop:
+				return
+			}
+		}
+	}()
+}
+
+func (s *SqlDB) checkReplicas(dbConfig Config) {
+	for _, r := range s.replicas {
+		r.setHealthy(pingOnce(context.Background(), r.db, dbConfig))
+	}
+}
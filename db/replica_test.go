@@ -0,0 +1,167 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/callicoder/go-commons/logger"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMockReplica(t *testing.T) (*replica, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
+	require.NoError(t, err)
+	return &replica{db: sqlx.NewDb(db, "postgres"), addr: "replica:5432", healthy: 1}, mock
+}
+
+func TestReplicas(t *testing.T) {
+	logger.SetupRootLogger(logger.Config{Level: "panic"})
+	ctx := ReadOnly(context.Background())
+	query := "SELECT name FROM users"
+
+	setup := func(t *testing.T) (*SqlDB, sqlmock.Sqlmock, []sqlmock.Sqlmock) {
+		store, mock := newMockDB(t, "postgres")
+		first, firstMock := newMockReplica(t)
+		second, secondMock := newMockReplica(t)
+		store.replicas = []*replica{first, second}
+		return store, mock, []sqlmock.Sqlmock{firstMock, secondMock}
+	}
+	rows := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"name"}).AddRow("sachin")
+	}
+
+	t.Run("should balance reads across replicas", func(t *testing.T) {
+		store, mock, replicaMocks := setup(t)
+		for _, replicaMock := range replicaMocks {
+			replicaMock.ExpectQuery(query).WillReturnRows(rows())
+		}
+		mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(1, 1))
+
+		var names []string
+		assert.NoError(t, store.SelectContext(ctx, &names, query))
+		var name string
+		assert.NoError(t, store.GetContext(ctx, &name, query))
+		_, err := store.ExecContext(ctx, "INSERT INTO users (name) VALUES ($1)", "sachin")
+		assert.NoError(t, err)
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		for _, replicaMock := range replicaMocks {
+			assert.NoError(t, replicaMock.ExpectationsWereMet())
+		}
+	})
+
+	t.Run("should read from the primary when forced", func(t *testing.T) {
+		store, mock, _ := setup(t)
+		mock.ExpectQuery(query).WillReturnRows(rows())
+
+		var name string
+		assert.NoError(t, store.GetContext(ForcePrimary(ctx), &name, query))
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should read from the primary unless the context is read-only", func(t *testing.T) {
+		store, mock, replicaMocks := setup(t)
+		mock.ExpectQuery(query).WillReturnRows(rows())
+		mock.ExpectQuery("SELECT nextval").WillReturnRows(sqlmock.NewRows([]string{"nextval"}).AddRow(7))
+
+		var name string
+		assert.NoError(t, store.GetContext(context.Background(), &name, query))
+		var id int
+		assert.NoError(t, store.GetContext(context.Background(), &id, "SELECT nextval('users_id_seq')"))
+
+		assert.Equal(t, 7, id)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		for _, replicaMock := range replicaMocks {
+			assert.NoError(t, replicaMock.ExpectationsWereMet())
+		}
+	})
+
+	t.Run("should run locking selects on the primary", func(t *testing.T) {
+		store, mock, replicaMocks := setup(t)
+		mock.ExpectQuery("SELECT name FROM users WHERE id").WillReturnRows(rows())
+		mock.ExpectQuery("SELECT name FROM users WHERE id").WillReturnRows(rows())
+
+		var name string
+		assert.NoError(t, store.GetContext(ctx, &name, "SELECT name FROM users WHERE id = $1 FOR UPDATE", 1))
+		var names []string
+		assert.NoError(t, store.SelectContext(ctx, &names, "SELECT name FROM users WHERE id = $1\n\tfor  share SKIP LOCKED", 1))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		for _, replicaMock := range replicaMocks {
+			assert.NoError(t, replicaMock.ExpectationsWereMet())
+		}
+	})
+
+	t.Run("should run statements which are not selects on the primary", func(t *testing.T) {
+		store, mock, replicaMocks := setup(t)
+		mock.ExpectQuery("INSERT INTO users").WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(1))
+		mock.ExpectQuery("WITH deleted AS").WillReturnRows(rows())
+		replicaMocks[1].ExpectQuery("(?i)select name FROM users").WillReturnRows(rows())
+
+		var id int
+		assert.NoError(t, store.GetContext(ctx, &id, "INSERT INTO users (name) VALUES ($1) RETURNING id", "sachin"))
+		var names []string
+		assert.NoError(t, store.SelectContext(ctx, &names, "WITH deleted AS (DELETE FROM users RETURNING name) SELECT name FROM deleted"))
+		var name string
+		assert.NoError(t, store.GetContext(ctx, &name, "  select name FROM users"))
+
+		assert.Equal(t, 1, id)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		for _, replicaMock := range replicaMocks {
+			assert.NoError(t, replicaMock.ExpectationsWereMet())
+		}
+	})
+
+	t.Run("should read in the transaction carried by the context", func(t *testing.T) {
+		store, mock, _ := setup(t)
+		mock.ExpectBegin()
+		mock.ExpectQuery(query).WillReturnRows(rows())
+		mock.ExpectCommit()
+
+		err := WithTx(ctx, store, nil, func(ctx context.Context, _ SqlStore) error {
+			var name string
+			return store.GetContext(ctx, &name, query)
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should fall back to the primary when replicas are unhealthy", func(t *testing.T) {
+		store, mock, replicaMocks := setup(t)
+		for _, replicaMock := range replicaMocks {
+			replicaMock.ExpectPing().WillReturnError(errors.New("connection refused"))
+		}
+		mock.ExpectQuery(query).WillReturnRows(rows())
+
+		store.checkReplicas(Config{})
+		var name string
+		assert.NoError(t, store.GetContext(ctx, &name, query))
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+		for i, replicaMock := range replicaMocks {
+			assert.False(t, store.replicas[i].isHealthy())
+			assert.NoError(t, replicaMock.ExpectationsWereMet())
+		}
+	})
+
+	t.Run("should read from replicas passing health checks again", func(t *testing.T) {
+		store, _, replicaMocks := setup(t)
+		store.replicas[0].healthy = 0
+		replicaMocks[0].ExpectPing()
+		replicaMocks[1].ExpectPing().WillReturnError(errors.New("connection refused"))
+		replicaMocks[0].ExpectQuery(query).WillReturnRows(rows())
+
+		store.checkReplicas(Config{})
+		var name string
+		assert.NoError(t, store.GetContext(ctx, &name, query))
+
+		for _, replicaMock := range replicaMocks {
+			assert.NoError(t, replicaMock.ExpectationsWereMet())
+		}
+	})
+}
@@ -4,7 +4,14 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"fmt"
+	"sync"
+	"time"
 
+	commonerrors "github.com/callicoder/go-commons/errors"
+	"github.com/callicoder/go-commons/errors/codes"
+	"github.com/callicoder/go-commons/logger"
+	"github.com/callicoder/go-commons/retry"
 	"github.com/jmoiron/sqlx"
 
 	// register pgx driver name
@@ -19,6 +26,14 @@ const (
 	ErrCantCloseTransaction = "can't close transaction"
 )
 
+// savepointDrivers lists the drivers supporting SAVEPOINT, RELEASE SAVEPOINT and ROLLBACK TO SAVEPOINT
+var savepointDrivers = map[string]bool{
+	"postgres": true,
+	"pgx":      true,
+	"mysql":    true,
+	"sqlite3":  true,
+}
+
 type SqlStore interface {
 	GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
 	SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error
@@ -30,44 +45,165 @@ type SqlStore interface {
 }
 
 type SqlDB struct {
-	db *sqlx.DB
+	db       *sqlx.DB
+	replicas []*replica
+	// next is the round-robin counter of the replicas
+	next     uint32
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
 }
 
+// SqlTx is a transaction, or a nested transaction backed by a savepoint when begun
+// from another SqlTx.
 type SqlTx struct {
 	tx *sqlx.Tx
+	// store is the SqlDB the transaction was begun on
+	store *SqlDB
+	// savepoint is the name of the savepoint of a nested transaction
+	savepoint string
+	// savepoints counts the savepoints created in the root transaction, to name them
+	savepoints *int
+	done       bool
 }
 
+const defaultConnectTimeoutMs = 5000
+
+// New opens a connection pool to the database and pings it, retrying as configured
+// by dbConfig.ConnectRetry. Pools are also opened to the replicas, whose health is
+// checked in the background every dbConfig.ReplicaHealthCheckIntervalMs.
 func New(dbConfig Config) (*SqlDB, error) {
-	db, err := sqlx.Open(dbConfig.Driver, dbConfig.URL())
+	db, err := open(dbConfig)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := ping(db, dbConfig); err != nil {
+		db.Close()
+		return nil, commonerrors.WithCode(codes.Unavailable).Wrapf(err, "failed to connect to database %s on %s:%d", dbConfig.Name, dbConfig.Host, dbConfig.Port)
+	}
+
 	sqlDb := &SqlDB{db: db}
+	for _, replicaConfig := range dbConfig.Replicas {
+		r, err := openReplica(dbConfig.replica(replicaConfig))
+		if err != nil {
+			sqlDb.Close()
+			return nil, err
+		}
+		sqlDb.replicas = append(sqlDb.replicas, r)
+	}
+	sqlDb.startHealthChecks(dbConfig)
+
 	return sqlDb, nil
 }
 
+func open(dbConfig Config) (*sqlx.DB, error) {
+	db, err := sqlx.Open(dbConfig.Driver, dbConfig.URL())
+	if err != nil {
+		return nil, commonerrors.Wrapf(err, "failed to open database %s on %s:%d", dbConfig.Name, dbConfig.Host, dbConfig.Port)
+	}
+
+	// Zero values keep the database/sql defaults
+	if dbConfig.MaxIdleConnections > 0 {
+		db.SetMaxIdleConns(dbConfig.MaxIdleConnections)
+	}
+	if dbConfig.MaxOpenConnections > 0 {
+		db.SetMaxOpenConns(dbConfig.MaxOpenConnections)
+	}
+	db.SetConnMaxLifetime(time.Duration(dbConfig.ConnMaxLifetimeMs) * time.Millisecond)
+	db.SetConnMaxIdleTime(time.Duration(dbConfig.ConnMaxIdleTimeMs) * time.Millisecond)
+
+	return db, nil
+}
+
+func ping(db *sqlx.DB, dbConfig Config) error {
+	retrier, err := retry.NewFromConfig(dbConfig.ConnectRetry, retry.WithOnRetry(retry.LogAttempts("database ping")))
+	if err != nil {
+		return err
+	}
+
+	return retrier.Do(context.Background(), func(ctx context.Context) error {
+		return pingOnce(ctx, db, dbConfig)
+	})
+}
+
+func pingOnce(ctx context.Context, db *sqlx.DB, dbConfig Config) error {
+	timeoutMs := dbConfig.ConnectTimeoutMs
+	if timeoutMs <= 0 {
+		timeoutMs = defaultConnectTimeoutMs
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeoutMs)*time.Millisecond)
+	defer cancel()
+	return db.PingContext(ctx)
+}
+
+func (s *SqlDB) PingContext(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
+// GetContext runs the query through the transaction carried by ctx, if it was begun on s,
+// and otherwise on the primary, or on a healthy replica when ctx is ReadOnly, see ReadOnly.
 func (s *SqlDB) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
-	return s.db.GetContext(ctx, dest, query, args...)
+	if tx, ok := s.txFromContext(ctx); ok {
+		return tx.GetContext(ctx, dest, query, args...)
+	}
+	return logQueryError(ctx, query, s.reader(ctx, query).GetContext(ctx, dest, query, args...))
 }
 
+// SelectContext runs the query through the transaction carried by ctx, if it was begun on s,
+// and otherwise on the primary, or on a healthy replica when ctx is ReadOnly, see ReadOnly.
 func (s *SqlDB) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
-	return s.db.SelectContext(ctx, dest, query, args...)
+	if tx, ok := s.txFromContext(ctx); ok {
+		return tx.SelectContext(ctx, dest, query, args...)
+	}
+	return logQueryError(ctx, query, s.reader(ctx, query).SelectContext(ctx, dest, query, args...))
 }
 
+// ExecContext runs the query through the transaction carried by ctx, if it was begun on s.
 func (s *SqlDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	return s.db.ExecContext(ctx, query, args...)
+	if tx, ok := s.txFromContext(ctx); ok {
+		return tx.ExecContext(ctx, query, args...)
+	}
+	res, err := s.db.ExecContext(ctx, query, args...)
+	return res, logQueryError(ctx, query, err)
 }
 
+// Begin starts a transaction, or a nested transaction of the transaction carried by ctx
+// if it was begun on s.
 func (s *SqlDB) Begin(ctx context.Context, opts *sql.TxOptions) (*SqlTx, error) {
+	if tx, ok := s.txFromContext(ctx); ok {
+		return tx.Begin(ctx, opts)
+	}
+
 	tx, err := s.db.BeginTxx(ctx, opts)
 	if err != nil {
 		return nil, err
 	}
-	sqlTx := &SqlTx{tx}
+	sqlTx := &SqlTx{tx: tx, store: s, savepoints: new(int)}
 	return sqlTx, nil
 }
 
+// txFromContext returns the transaction carried by ctx when it belongs to s, so that a
+// transaction of another database is never used.
+func (s *SqlDB) txFromContext(ctx context.Context) (*SqlTx, bool) {
+	tx, ok := TxFromContext(ctx)
+	if !ok || tx.store != s {
+		return nil, false
+	}
+	return tx, true
+}
+
+// logQueryError logs err, returned by query, with the logger of ctx, so that it can be
+// joined with the other logs of the request. No rows and cancelled requests are not logged.
+func logQueryError(ctx context.Context, query string, err error) error {
+	if err == nil || errors.Is(err, sql.ErrNoRows) || errors.Is(err, context.Canceled) {
+		return err
+	}
+	logger.FromContextOrStd(ctx).WithFields(logger.Fields{"query": query}).Warnf("Database query failed: %v", err)
+	return err
+}
+
 func (s *SqlDB) Commit() error {
 	return errors.New(ErrInvalidTransaction)
 }
@@ -76,32 +212,90 @@ func (s *SqlDB) Rollback() error {
 	return errors.New(ErrInvalidTransaction)
 }
 
+// Close stops the replica health checks and closes the replicas and the primary.
 func (s *SqlDB) Close() error {
-	return s.db.Close()
+	if s.stop != nil {
+		s.stopOnce.Do(func() { close(s.stop) })
+		s.wg.Wait()
+	}
+
+	var closeErr error
+	for _, r := range s.replicas {
+		if err := r.db.Close(); err != nil && closeErr == nil {
+			closeErr = err
+		}
+	}
+	if err := s.db.Close(); err != nil && closeErr == nil {
+		closeErr = err
+	}
+	return closeErr
 }
 
 func (s *SqlTx) GetContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
-	return s.tx.GetContext(ctx, dest, query, args...)
+	return logQueryError(ctx, query, s.tx.GetContext(ctx, dest, query, args...))
 }
 
 func (s *SqlTx) SelectContext(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
-	return s.tx.SelectContext(ctx, dest, query, args...)
+	return logQueryError(ctx, query, s.tx.SelectContext(ctx, dest, query, args...))
 }
 
 func (s *SqlTx) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
-	return s.tx.ExecContext(ctx, query, args...)
+	res, err := s.tx.ExecContext(ctx, query, args...)
+	return res, logQueryError(ctx, query, err)
 }
 
+// Begin starts a nested transaction by creating a savepoint. Committing it releases the
+// savepoint and rolling it back rolls back to the savepoint, while the outer transaction
+// goes on. opts are ignored as they can only be set on the outer transaction.
 func (s *SqlTx) Begin(ctx context.Context, opts *sql.TxOptions) (*SqlTx, error) {
-	return nil, errors.New(ErrCantStartTransaction)
+	if s.done {
+		return nil, sql.ErrTxDone
+	}
+	if !SupportsSavepoints(s.tx.DriverName()) {
+		return nil, fmt.Errorf("%s: driver %s does not support savepoints", ErrCantStartTransaction, s.tx.DriverName())
+	}
+
+	*s.savepoints++
+	savepoint := fmt.Sprintf("sp_%d", *s.savepoints)
+	if _, err := s.tx.ExecContext(ctx, "SAVEPOINT "+savepoint); err != nil {
+		return nil, fmt.Errorf("%w :: Failed to create savepoint %s", err, savepoint)
+	}
+
+	return &SqlTx{tx: s.tx, store: s.store, savepoint: savepoint, savepoints: s.savepoints}, nil
 }
 
 func (s *SqlTx) Commit() error {
-	return s.tx.Commit()
+	if s.savepoint == "" {
+		return s.tx.Commit()
+	}
+	if s.done {
+		return sql.ErrTxDone
+	}
+	s.done = true
+	_, err := s.tx.Exec("RELEASE SAVEPOINT " + s.savepoint)
+	return err
 }
 
 func (s *SqlTx) Rollback() error {
-	return s.tx.Rollback()
+	if s.savepoint == "" {
+		return s.tx.Rollback()
+	}
+	if s.done {
+		return sql.ErrTxDone
+	}
+	s.done = true
+	_, err := s.tx.Exec("ROLLBACK TO SAVEPOINT " + s.savepoint)
+	return err
+}
+
+// Nested reports whether the transaction is backed by a savepoint of an outer transaction.
+func (s *SqlTx) Nested() bool {
+	return s.savepoint != ""
+}
+
+// SupportsSavepoints reports whether nested transactions can be started with the driver.
+func SupportsSavepoints(driverName string) bool {
+	return savepointDrivers[driverName]
 }
 
 func (s *SqlTx) Close() error {
@@ -0,0 +1,27 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/callicoder/go-commons/logger"
+	"github.com/callicoder/go-commons/requestutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogQueryError(t *testing.T) {
+	l := newRecordingLogger()
+	ctx := logger.NewContext(requestutil.WithRequestID(context.Background(), "abc-123"), l)
+	errDeadlock := errors.New("deadlock detected")
+
+	assert.Equal(t, errDeadlock, logQueryError(ctx, "UPDATE users SET name = $1", errDeadlock))
+	assert.Equal(t, sql.ErrNoRows, logQueryError(ctx, "SELECT name FROM users", sql.ErrNoRows))
+	assert.Equal(t, context.Canceled, logQueryError(ctx, "SELECT name FROM users", context.Canceled))
+	assert.NoError(t, logQueryError(ctx, "SELECT name FROM users", nil))
+
+	assert.Equal(t, []logger.Fields{
+		{"msg": "Database query failed: deadlock detected", "query": "UPDATE users SET name = $1", logger.FieldRequestID: "abc-123"},
+	}, *l.entries)
+}
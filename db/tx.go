@@ -0,0 +1,63 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"github.com/callicoder/go-commons/logger"
+)
+
+// WithTx runs fn in a transaction begun on store, which is committed if fn succeeds and
+// rolled back if it returns an error or panics, in which case the panic is propagated.
+// When store already is a transaction, or ctx carries a transaction of store, fn runs in
+// a nested transaction backed by a savepoint, or joins the transaction if the driver does
+// not support savepoints. The context passed to fn carries the transaction, so queries
+// run through the SqlDB with it take part in the transaction.
+func WithTx(ctx context.Context, store SqlStore, opts *sql.TxOptions, fn func(ctx context.Context, tx SqlStore) error) error {
+	if outer, ok := outerTx(ctx, store); ok && !SupportsSavepoints(outer.tx.DriverName()) {
+		return fn(NewTxContext(ctx, outer), outer)
+	}
+
+	tx, err := store.Begin(ctx, opts)
+	if err != nil {
+		return err
+	}
+	ctx = NewTxContext(ctx, tx)
+
+	// fn may also exit its goroutine with runtime.Goexit, e.g. through t.FailNow
+	committed := false
+	defer func() {
+		if committed {
+			return
+		}
+		rec := recover()
+		rollback(ctx, tx)
+		if rec != nil {
+			panic(rec)
+		}
+	}()
+
+	if err := fn(ctx, tx); err != nil {
+		return err
+	}
+
+	committed = true
+	return tx.Commit()
+}
+
+// outerTx returns the transaction a transaction begun on store would be nested in.
+func outerTx(ctx context.Context, store SqlStore) (*SqlTx, bool) {
+	switch s := store.(type) {
+	case *SqlTx:
+		return s, true
+	case *SqlDB:
+		return s.txFromContext(ctx)
+	}
+	return nil, false
+}
+
+func rollback(ctx context.Context, tx *SqlTx) {
+	if err := tx.Rollback(); err != nil && err != sql.ErrTxDone {
+		logger.FromContextOrStd(ctx).Errorf("Failed to rollback transaction: %v", err)
+	}
+}
@@ -0,0 +1,213 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"runtime"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/callicoder/go-commons/logger"
+	"github.com/jmoiron/sqlx"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newMockDB(t *testing.T, driverName string) (*SqlDB, sqlmock.Sqlmock) {
+	db, mock, err := sqlmock.New()
+	require.NoError(t, err)
+	return &SqlDB{db: sqlx.NewDb(db, driverName)}, mock
+}
+
+func TestWithTx(t *testing.T) {
+	logger.SetupRootLogger(logger.Config{Level: "panic"})
+	ctx := context.Background()
+
+	t.Run("should commit when fn succeeds", func(t *testing.T) {
+		store, mock := newMockDB(t, "postgres")
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		err := WithTx(ctx, store, nil, func(ctx context.Context, tx SqlStore) error {
+			_, err := tx.ExecContext(ctx, "INSERT INTO users (name) VALUES ($1)", "sachin")
+			return err
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should rollback when fn fails", func(t *testing.T) {
+		store, mock := newMockDB(t, "postgres")
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		errFailed := errors.New("failed")
+		err := WithTx(ctx, store, nil, func(ctx context.Context, tx SqlStore) error {
+			return errFailed
+		})
+
+		assert.Equal(t, errFailed, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should rollback and propagate panics", func(t *testing.T) {
+		store, mock := newMockDB(t, "postgres")
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		assert.PanicsWithValue(t, "boom", func() {
+			_ = WithTx(ctx, store, nil, func(ctx context.Context, tx SqlStore) error {
+				panic("boom")
+			})
+		})
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should propagate panics when the rollback fails", func(t *testing.T) {
+		store, mock := newMockDB(t, "postgres")
+		mock.ExpectBegin()
+		mock.ExpectRollback().WillReturnError(errors.New("connection reset"))
+
+		assert.PanicsWithValue(t, "boom", func() {
+			_ = WithTx(ctx, store, nil, func(ctx context.Context, tx SqlStore) error {
+				panic("boom")
+			})
+		})
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should rollback when fn exits its goroutine", func(t *testing.T) {
+		store, mock := newMockDB(t, "postgres")
+		mock.ExpectBegin()
+		mock.ExpectRollback()
+
+		done := make(chan struct{})
+		go func() {
+			defer close(done)
+			_ = WithTx(ctx, store, nil, func(ctx context.Context, tx SqlStore) error {
+				runtime.Goexit()
+				return nil
+			})
+		}()
+		<-done
+
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should nest transactions with savepoints", func(t *testing.T) {
+		store, mock := newMockDB(t, "postgres")
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("ROLLBACK TO SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("SAVEPOINT sp_2").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT sp_2").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		errFailed := errors.New("failed")
+		err := WithTx(ctx, store, nil, func(ctx context.Context, outer SqlStore) error {
+			err := WithTx(ctx, outer, nil, func(ctx context.Context, inner SqlStore) error {
+				return errFailed
+			})
+			assert.Equal(t, errFailed, err)
+
+			return WithTx(ctx, outer, nil, func(ctx context.Context, inner SqlStore) error {
+				return nil
+			})
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should join an outer transaction when savepoints are not supported", func(t *testing.T) {
+		store, mock := newMockDB(t, "sqlmock")
+		mock.ExpectBegin()
+		mock.ExpectCommit()
+
+		err := WithTx(ctx, store, nil, func(ctx context.Context, outer SqlStore) error {
+			return WithTx(ctx, outer, nil, func(ctx context.Context, inner SqlStore) error {
+				assert.Equal(t, outer, inner)
+				return nil
+			})
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestSqlTxBegin(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should fail for drivers without savepoints", func(t *testing.T) {
+		store, mock := newMockDB(t, "sqlmock")
+		mock.ExpectBegin()
+
+		tx, err := store.Begin(ctx, nil)
+		require.NoError(t, err)
+
+		_, err = tx.Begin(ctx, nil)
+		assert.EqualError(t, err, ErrCantStartTransaction+": driver sqlmock does not support savepoints")
+	})
+
+	t.Run("should not close a nested transaction twice", func(t *testing.T) {
+		store, mock := newMockDB(t, "postgres")
+		mock.ExpectBegin()
+		mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+
+		tx, err := store.Begin(ctx, nil)
+		require.NoError(t, err)
+		nested, err := tx.Begin(ctx, nil)
+		require.NoError(t, err)
+
+		assert.True(t, nested.Nested())
+		assert.NoError(t, nested.Commit())
+		assert.Equal(t, sql.ErrTxDone, nested.Rollback())
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestTxContext(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("should route queries through the transaction in the context", func(t *testing.T) {
+		store, mock := newMockDB(t, "postgres")
+		mock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectExec("SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("RELEASE SAVEPOINT sp_1").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectCommit()
+
+		err := WithTx(ctx, store, nil, func(ctx context.Context, _ SqlStore) error {
+			if _, err := store.ExecContext(ctx, "INSERT INTO users (name) VALUES ($1)", "sachin"); err != nil {
+				return err
+			}
+			return WithTx(ctx, store, nil, func(ctx context.Context, tx SqlStore) error {
+				assert.True(t, tx.(*SqlTx).Nested())
+				return nil
+			})
+		})
+
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("should ignore transactions of another database", func(t *testing.T) {
+		store, mock := newMockDB(t, "postgres")
+		other, otherMock := newMockDB(t, "postgres")
+		otherMock.ExpectBegin()
+		mock.ExpectExec("INSERT INTO users").WillReturnResult(sqlmock.NewResult(1, 1))
+
+		tx, err := other.Begin(ctx, nil)
+		require.NoError(t, err)
+
+		_, err = store.ExecContext(NewTxContext(ctx, tx), "INSERT INTO users (name) VALUES ($1)", "sachin")
+		assert.NoError(t, err)
+		assert.NoError(t, mock.ExpectationsWereMet())
+		assert.NoError(t, otherMock.ExpectationsWereMet())
+	})
+}
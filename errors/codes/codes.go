@@ -11,6 +11,12 @@ const (
 	NotFound     = "not_found"
 	Conflict     = "conflict"
 	Internal     = "internal"
+
+	TooManyRequests = "too_many_requests"
+	PayloadTooLarge = "payload_too_large"
+	CircuitOpen     = "circuit_open"
+	Timeout         = "timeout"
+	Unavailable     = "unavailable"
 )
 
 var codeToHttpStatus = map[string]int64{
@@ -19,6 +25,12 @@ var codeToHttpStatus = map[string]int64{
 	Forbidden:    http.StatusForbidden,
 	NotFound:     http.StatusNotFound,
 	Conflict:     http.StatusConflict,
+
+	TooManyRequests: http.StatusTooManyRequests,
+	PayloadTooLarge: http.StatusRequestEntityTooLarge,
+	CircuitOpen:     http.StatusServiceUnavailable,
+	Timeout:         http.StatusGatewayTimeout,
+	Unavailable:     http.StatusServiceUnavailable,
 }
 
 func HttpStatus(code string) int64 {
@@ -27,3 +39,27 @@ func HttpStatus(code string) int64 {
 	}
 	return http.StatusInternalServerError
 }
+
+var httpStatusToCode = map[int]string{
+	http.StatusBadRequest:            BadRequest,
+	http.StatusUnauthorized:          Unauthorized,
+	http.StatusForbidden:             Forbidden,
+	http.StatusNotFound:              NotFound,
+	http.StatusConflict:              Conflict,
+	http.StatusTooManyRequests:       TooManyRequests,
+	http.StatusRequestEntityTooLarge: PayloadTooLarge,
+	http.StatusServiceUnavailable:    Unavailable,
+	http.StatusGatewayTimeout:        Timeout,
+}
+
+// FromHttpStatus returns the code matching an http status, falling back to
+// BadRequest for client errors and Internal otherwise.
+func FromHttpStatus(status int) string {
+	if code, ok := httpStatusToCode[status]; ok {
+		return code
+	}
+	if status >= 400 && status < 500 {
+		return BadRequest
+	}
+	return Internal
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/callicoder/go-commons/errors"
+	"github.com/callicoder/go-commons/errors/codes"
 	"github.com/callicoder/go-commons/logger"
 )
 
@@ -30,3 +32,23 @@ func Error(w http.ResponseWriter, statusCode int, err error) {
 	}
 	JSON(w, statusCode, httpErr)
 }
+
+// BaseError writes err as an errors.BaseError JSON body with the http status mapped from its code.
+// Errors which are not BaseErrors are reported with the codes.Internal code.
+func BaseError(w http.ResponseWriter, err error) {
+	baseErr := toBaseError(err)
+	JSON(w, int(codes.HttpStatus(baseErr.Code)), baseErr)
+}
+
+func toBaseError(err error) *errors.BaseError {
+	switch e := err.(type) {
+	case *errors.BaseError:
+		return e
+	case *errors.BaseErrorStack:
+		return e.BaseError
+	}
+	return &errors.BaseError{
+		Code:    codes.Internal,
+		Message: err.Error(),
+	}
+}
@@ -0,0 +1,63 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/callicoder/go-commons/db"
+	"github.com/callicoder/go-commons/redis"
+	"github.com/callicoder/go-commons/statsd"
+)
+
+// DB checks that the database answers to a ping.
+func DB(sqlDB *db.SqlDB) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		return sqlDB.PingContext(ctx)
+	})
+}
+
+// Redis checks that redis answers to a PING and, in cluster mode, that the cluster state is ok.
+func Redis(client redis.Client) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		if err := client.Ping(ctx).Err(); err != nil {
+			return err
+		}
+		if !client.ClusterMode() {
+			return nil
+		}
+
+		info, err := client.ClusterInfo(ctx).Result()
+		if err != nil {
+			return err
+		}
+		if !strings.Contains(info, "cluster_state:ok") {
+			return fmt.Errorf("redis cluster state is not ok")
+		}
+		return nil
+	})
+}
+
+// Statsd checks that the metrics client is set up and not closed, and flushes the metrics
+// buffered by a *statsd.Reporter, without emitting any metric. Metrics are sent over UDP
+// with the statsd backend, so an unreachable statsd server can not be detected.
+func Statsd(client statsd.Client) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		if client == nil {
+			return errors.New("statsd client is not configured")
+		}
+		reporter, isReporter := client.(*statsd.Reporter)
+		if isReporter && (reporter == nil || reporter.Client == nil) {
+			return errors.New("statsd reporter has no client")
+		}
+		if closer, ok := client.(interface{ Closed() bool }); ok && closer.Closed() {
+			return errors.New("statsd client is closed")
+		}
+
+		if isReporter {
+			return reporter.Client.Flush()
+		}
+		return nil
+	})
+}
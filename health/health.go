@@ -0,0 +1,208 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/callicoder/go-commons/handler/response"
+)
+
+type Status string
+
+const (
+	StatusUp   Status = "up"
+	StatusDown Status = "down"
+)
+
+const defaultTimeoutMs = 2000
+
+// Checker checks the health of a component, returning an error when it is unhealthy.
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error {
+	return f(ctx)
+}
+
+type Config struct {
+	// TimeoutMs bounds the duration of every check, defaults to 2000
+	TimeoutMs int `mapstructure:"timeout_ms"`
+	// CacheTTLMs is how long a check result is reused. Zero disables caching.
+	CacheTTLMs int `mapstructure:"cache_ttl_ms"`
+}
+
+type ComponentStatus struct {
+	Status     Status  `json:"status"`
+	Error      string  `json:"error,omitempty"`
+	DurationMs float64 `json:"duration_ms"`
+}
+
+type Report struct {
+	Status     Status                     `json:"status"`
+	Components map[string]ComponentStatus `json:"components,omitempty"`
+}
+
+type check struct {
+	name    string
+	checker Checker
+
+	mu        sync.Mutex
+	result    ComponentStatus
+	checkedAt time.Time
+}
+
+// Registry holds the liveness and readiness checks of a service.
+// Liveness checks should only fail when the process must be restarted, while
+// readiness checks fail when the service can't serve traffic, e.g. its database is down.
+type Registry struct {
+	timeout time.Duration
+	ttl     time.Duration
+
+	mu        sync.RWMutex
+	liveness  []*check
+	readiness []*check
+}
+
+func NewRegistry(c Config) *Registry {
+	timeoutMs := c.TimeoutMs
+	if timeoutMs <= 0 {
+		timeoutMs = defaultTimeoutMs
+	}
+	return &Registry{
+		timeout: time.Duration(timeoutMs) * time.Millisecond,
+		ttl:     time.Duration(c.CacheTTLMs) * time.Millisecond,
+	}
+}
+
+func (r *Registry) AddLivenessCheck(name string, checker Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.liveness = append(r.liveness, &check{name: name, checker: checker})
+}
+
+func (r *Registry) AddReadinessCheck(name string, checker Checker) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.readiness = append(r.readiness, &check{name: name, checker: checker})
+}
+
+func (r *Registry) Liveness(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := r.liveness
+	r.mu.RUnlock()
+	return r.run(ctx, checks)
+}
+
+func (r *Registry) Readiness(ctx context.Context) Report {
+	r.mu.RLock()
+	checks := r.readiness
+	r.mu.RUnlock()
+	return r.run(ctx, checks)
+}
+
+// LivenessHandler responds with the liveness report, with a 200 status when all the
+// checks pass and 503 otherwise.
+func (r *Registry) LivenessHandler() http.Handler {
+	return reportHandler(r.Liveness)
+}
+
+// ReadinessHandler responds with the readiness report, with a 200 status when all the
+// checks pass and 503 otherwise.
+func (r *Registry) ReadinessHandler() http.Handler {
+	return reportHandler(r.Readiness)
+}
+
+func reportHandler(report func(ctx context.Context) Report) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		rep := report(req.Context())
+		status := http.StatusOK
+		if rep.Status != StatusUp {
+			status = http.StatusServiceUnavailable
+		}
+		response.JSON(w, status, rep)
+	})
+}
+
+func (r *Registry) run(ctx context.Context, checks []*check) Report {
+	report := Report{
+		Status:     StatusUp,
+		Components: make(map[string]ComponentStatus, len(checks)),
+	}
+
+	results := make([]ComponentStatus, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func(i int, c *check) {
+			defer wg.Done()
+			results[i] = r.runCheck(ctx, c)
+		}(i, c)
+	}
+	wg.Wait()
+
+	for i, c := range checks {
+		report.Components[c.name] = results[i]
+		if results[i].Status != StatusUp {
+			report.Status = StatusDown
+		}
+	}
+	return report
+}
+
+func (r *Registry) runCheck(ctx context.Context, c *check) ComponentStatus {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if r.ttl > 0 && !c.checkedAt.IsZero() && time.Since(c.checkedAt) < r.ttl {
+		return c.result
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ctx, r.timeout)
+	defer cancel()
+
+	start := time.Now()
+	errs := make(chan error, 1)
+	go func() {
+		defer func() {
+			if rec := recover(); rec != nil {
+				errs <- fmt.Errorf("check panicked: %v", rec)
+			}
+		}()
+		errs <- c.checker.Check(ctx)
+	}()
+
+	var err error
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+		err = fmt.Errorf("check timed out after %v", r.timeout)
+	}
+	// The caller gave up, e.g. the probe disconnected, which says nothing about the health
+	// of the component, so the result is not cached
+	cancelled := err != nil && parent.Err() != nil
+	if cancelled {
+		err = fmt.Errorf("check cancelled: %v", parent.Err())
+	}
+
+	result := ComponentStatus{
+		Status:     StatusUp,
+		DurationMs: float64(time.Since(start)) / float64(time.Millisecond),
+	}
+	if err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+
+	if !cancelled {
+		c.result = result
+		c.checkedAt = time.Now()
+	}
+	return result
+}
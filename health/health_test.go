@@ -0,0 +1,114 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	datadog "github.com/DataDog/datadog-go/statsd"
+	"github.com/callicoder/go-commons/statsd"
+	"github.com/callicoder/go-commons/statsd/statsdtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry(t *testing.T) {
+	t.Run("should report every component and fail readiness when one is down", func(t *testing.T) {
+		registry := NewRegistry(Config{})
+		registry.AddLivenessCheck("process", CheckerFunc(func(ctx context.Context) error { return nil }))
+		registry.AddReadinessCheck("statsd", Statsd(statsdtest.NewClient()))
+		registry.AddReadinessCheck("db", CheckerFunc(func(ctx context.Context) error {
+			return errors.New("connection refused")
+		}))
+
+		rec := httptest.NewRecorder()
+		registry.ReadinessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/ready", nil))
+
+		assert.Equal(t, http.StatusServiceUnavailable, rec.Code)
+		report := Report{}
+		assert.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		assert.Equal(t, StatusDown, report.Status)
+		assert.Equal(t, StatusUp, report.Components["statsd"].Status)
+		assert.Equal(t, "connection refused", report.Components["db"].Error)
+
+		rec = httptest.NewRecorder()
+		registry.LivenessHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/health/live", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+
+	t.Run("should check statsd clients without emitting metrics", func(t *testing.T) {
+		stats := statsdtest.NewClient()
+
+		assert.NoError(t, Statsd(stats).Check(context.Background()))
+		assert.Empty(t, stats.Metrics())
+		assert.Error(t, Statsd(nil).Check(context.Background()))
+		assert.Error(t, Statsd(&statsd.Reporter{}).Check(context.Background()))
+		var reporter *statsd.Reporter
+		assert.EqualError(t, Statsd(reporter).Check(context.Background()), "statsd reporter has no client")
+		var client *datadog.Client
+		assert.Equal(t, datadog.ErrNoClient, Statsd(&statsd.Reporter{Client: client}).Check(context.Background()))
+	})
+
+	t.Run("should fail once the statsd client is closed", func(t *testing.T) {
+		stats := statsdtest.NewClient()
+		reporter, err := statsd.NewClient(statsd.Config{Host: "127.0.0.1", Port: 8125, Enabled: true})
+		require.NoError(t, err)
+
+		assert.NoError(t, Statsd(reporter).Check(context.Background()))
+		assert.NoError(t, stats.Close())
+		assert.NoError(t, reporter.Close())
+
+		assert.EqualError(t, Statsd(stats).Check(context.Background()), "statsd client is closed")
+		assert.EqualError(t, Statsd(reporter).Check(context.Background()), "statsd client is closed")
+	})
+
+	t.Run("should time out slow checks", func(t *testing.T) {
+		registry := NewRegistry(Config{TimeoutMs: 10})
+		registry.AddReadinessCheck("slow", CheckerFunc(func(ctx context.Context) error {
+			<-ctx.Done()
+			time.Sleep(50 * time.Millisecond)
+			return nil
+		}))
+
+		report := registry.Readiness(context.Background())
+
+		assert.Equal(t, StatusDown, report.Status)
+	})
+
+	t.Run("should not cache checks cancelled by the caller", func(t *testing.T) {
+		registry := NewRegistry(Config{TimeoutMs: 60000, CacheTTLMs: 60000})
+		registry.AddReadinessCheck("db", CheckerFunc(func(ctx context.Context) error {
+			return ctx.Err()
+		}))
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		report := registry.Readiness(ctx)
+
+		assert.Equal(t, StatusDown, report.Status)
+		assert.Equal(t, "check cancelled: context canceled", report.Components["db"].Error)
+
+		report = registry.Readiness(context.Background())
+
+		assert.Equal(t, StatusUp, report.Status)
+	})
+
+	t.Run("should cache results", func(t *testing.T) {
+		calls := 0
+		registry := NewRegistry(Config{CacheTTLMs: 60000})
+		registry.AddReadinessCheck("db", CheckerFunc(func(ctx context.Context) error {
+			calls++
+			return nil
+		}))
+
+		registry.Readiness(context.Background())
+		registry.Readiness(context.Background())
+
+		assert.Equal(t, 1, calls)
+	})
+}
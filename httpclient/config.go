@@ -0,0 +1,39 @@
+package httpclient
+
+type Config struct {
+	// Name identifies the downstream service in metrics and is the circuit breaker command name
+	Name      string
+	TimeoutMs int `mapstructure:"timeout_ms"`
+	// MaxRetries is the number of retries of idempotent requests failing with a
+	// network error or a 502, 503 or 504 status. Zero disables retries.
+	MaxRetries        int `mapstructure:"max_retries"`
+	RetryBackoffMs    int `mapstructure:"retry_backoff_ms"`
+	RetryMaxBackoffMs int `mapstructure:"retry_max_backoff_ms"`
+	// CircuitBreaker runs every attempt through the circuit breaker command Name,
+	// configured with the circuitbreaker package. Unless the command is configured
+	// beforehand, New configures it with a timeout of TimeoutMs instead of the
+	// circuit breaker default of 1s.
+	CircuitBreaker bool `mapstructure:"circuit_breaker"`
+}
+
+const (
+	defaultTimeoutMs         = 10000
+	defaultRetryBackoffMs    = 100
+	defaultRetryMaxBackoffMs = 2000
+)
+
+func (c Config) withDefaults() Config {
+	if c.Name == "" {
+		c.Name = "default"
+	}
+	if c.TimeoutMs <= 0 {
+		c.TimeoutMs = defaultTimeoutMs
+	}
+	if c.RetryBackoffMs <= 0 {
+		c.RetryBackoffMs = defaultRetryBackoffMs
+	}
+	if c.RetryMaxBackoffMs <= 0 {
+		c.RetryMaxBackoffMs = defaultRetryMaxBackoffMs
+	}
+	return c
+}
@@ -0,0 +1,28 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+)
+
+type contextKey int
+
+const headersKey contextKey = iota
+
+// WithHeaders returns a copy of ctx carrying headers which are set on every outbound
+// request made with it, e.g. authentication or tenant headers of the inbound request.
+func WithHeaders(ctx context.Context, header http.Header) context.Context {
+	merged := http.Header{}
+	for key, values := range headersFromContext(ctx) {
+		merged[key] = values
+	}
+	for key, values := range header {
+		merged[http.CanonicalHeaderKey(key)] = values
+	}
+	return context.WithValue(ctx, headersKey, merged)
+}
+
+func headersFromContext(ctx context.Context) http.Header {
+	header, _ := ctx.Value(headersKey).(http.Header)
+	return header
+}
@@ -0,0 +1,340 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/callicoder/go-commons/circuitbreaker"
+	"github.com/callicoder/go-commons/errors"
+	"github.com/callicoder/go-commons/errors/codes"
+	"github.com/callicoder/go-commons/logger"
+	"github.com/callicoder/go-commons/requestutil"
+	"github.com/callicoder/go-commons/retry"
+	"github.com/callicoder/go-commons/statsd"
+)
+
+const (
+	headerContentType = "Content-Type"
+	contentTypeJSON   = "application/json"
+
+	metricRequests = "http.client.requests"
+	metricLatency  = "http.client.latency"
+
+	// maxErrorBodyBytes limits how much of an error response is read to decode it
+	maxErrorBodyBytes = 1 << 20
+)
+
+var idempotentMethods = map[string]bool{
+	http.MethodGet:     true,
+	http.MethodHead:    true,
+	http.MethodOptions: true,
+	http.MethodTrace:   true,
+	http.MethodPut:     true,
+	http.MethodDelete:  true,
+}
+
+var retryableStatuses = map[int]bool{
+	http.StatusBadGateway:         true,
+	http.StatusServiceUnavailable: true,
+	http.StatusGatewayTimeout:     true,
+}
+
+// errServerError marks server errors as failures for the circuit breaker
+var errServerError = errors.New("server error")
+
+type Client struct {
+	client *http.Client
+	config Config
+	stats  statsd.Client
+}
+
+type Option func(*Client)
+
+func WithStatsd(client statsd.Client) Option {
+	return func(c *Client) {
+		c.stats = client
+	}
+}
+
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		c.client.Transport = transport
+	}
+}
+
+func New(conf Config, opts ...Option) *Client {
+	conf = conf.withDefaults()
+	if conf.CircuitBreaker && !circuitbreaker.Configured(conf.Name) {
+		circuitbreaker.ConfigureCommand(conf.Name, circuitbreaker.CommandConfig{TimeoutMs: conf.TimeoutMs})
+	}
+	c := &Client{
+		client: &http.Client{
+			Timeout: time.Duration(conf.TimeoutMs) * time.Millisecond,
+		},
+		config: conf,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Do sends req, retrying idempotent requests on transient failures. The request id and
+// the headers stored in the request context are propagated.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	requestutil.PropagateRequestID(ctx, req)
+	for key, values := range headersFromContext(ctx) {
+		if req.Header.Get(key) == "" {
+			req.Header[key] = values
+		}
+	}
+
+	if !c.isRetryable(req) || c.config.MaxRetries <= 0 {
+		return c.attempt(req)
+	}
+
+	var resp *http.Response
+	attempts := 0
+	err := retry.Do(ctx, func(ctx context.Context) error {
+		attempts++
+		if resp != nil {
+			// Previous attempt failed with a retryable status
+			drain(resp)
+			resp = nil
+		}
+		if attempts > 1 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return retry.Permanent(err)
+			}
+			req.Body = body
+		}
+
+		r, err := c.attempt(req)
+		if err != nil {
+			if circuitbreaker.IsOpen(err) {
+				return retry.Permanent(err)
+			}
+			return err
+		}
+		resp = r
+		if retryableStatuses[r.StatusCode] {
+			return &statusError{status: r.StatusCode}
+		}
+		return nil
+	}, c.retryOptions(req)...)
+
+	if _, ok := err.(*statusError); ok {
+		// Retries are exhausted, the caller handles the last response
+		return resp, nil
+	}
+	if err != nil {
+		if resp != nil {
+			drain(resp)
+		}
+		return nil, err
+	}
+	return resp, nil
+}
+
+// DoJSON sends a request with body encoded as JSON, and decodes the response into out
+// if it is not nil. Non 2xx responses are returned as BaseErrors, decoded from the
+// response body when the downstream service replied with one.
+func (c *Client) DoJSON(ctx context.Context, method, url string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		b, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(b)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set(headerContentType, contentTypeJSON)
+	}
+
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer drain(resp)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return DecodeError(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+func (c *Client) Get(ctx context.Context, url string, out interface{}) error {
+	return c.DoJSON(ctx, http.MethodGet, url, nil, out)
+}
+
+func (c *Client) Post(ctx context.Context, url string, body, out interface{}) error {
+	return c.DoJSON(ctx, http.MethodPost, url, body, out)
+}
+
+func (c *Client) Put(ctx context.Context, url string, body, out interface{}) error {
+	return c.DoJSON(ctx, http.MethodPut, url, body, out)
+}
+
+func (c *Client) Delete(ctx context.Context, url string, out interface{}) error {
+	return c.DoJSON(ctx, http.MethodDelete, url, nil, out)
+}
+
+// DecodeError converts a non 2xx response to a BaseError. The body is decoded when it
+// holds a BaseError, otherwise the code is derived from the status.
+func DecodeError(resp *http.Response) error {
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxErrorBodyBytes))
+	if err != nil {
+		return errors.WithCode(codes.FromHttpStatus(resp.StatusCode)).Wrapf(err, "failed to read error response with status %d", resp.StatusCode)
+	}
+
+	baseErr := &errors.BaseError{}
+	if json.Unmarshal(body, baseErr) == nil && baseErr.Code != "" {
+		return baseErr
+	}
+
+	return errors.WithCode(codes.FromHttpStatus(resp.StatusCode)).Newf("request failed with status %d", resp.StatusCode)
+}
+
+func (c *Client) attempt(req *http.Request) (*http.Response, error) {
+	if !c.config.CircuitBreaker {
+		return c.send(req)
+	}
+
+	// The attempt is cancelled when the breaker gives up on it, or once its response body is closed
+	ctx, cancel := context.WithCancel(req.Context())
+
+	var (
+		mu        sync.Mutex
+		resp      *http.Response
+		abandoned bool
+	)
+	err := circuitbreaker.Do(ctx, c.config.Name, func(ctx context.Context) error {
+		r, err := c.send(req.WithContext(ctx))
+		if err != nil {
+			return err
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		if abandoned {
+			drain(r)
+			return nil
+		}
+		resp = r
+		if r.StatusCode >= http.StatusInternalServerError {
+			return errServerError
+		}
+		return nil
+	}, nil)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if err != nil && err != errServerError {
+		// The breaker gave up on the request, e.g. on timeout
+		abandoned = true
+		if resp != nil {
+			drain(resp)
+		}
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (c *cancelOnClose) Close() error {
+	err := c.ReadCloser.Close()
+	c.cancel()
+	return err
+}
+
+func (c *Client) send(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := c.client.Do(req)
+	latency := time.Since(start)
+
+	if c.stats != nil {
+		status := "error"
+		if err == nil {
+			status = strconv.Itoa(resp.StatusCode)
+		}
+		tags := []string{
+			"client:" + c.config.Name,
+			"method:" + req.Method,
+			"host:" + req.URL.Hostname(),
+			"status:" + status,
+		}
+		if err := c.stats.IncrementWithTags(metricRequests, tags...); err != nil {
+			logger.Errorf("Failed to report request metric: %v", err)
+		}
+		if err := c.stats.TimingWithTags(metricLatency, latency, tags...); err != nil {
+			logger.Errorf("Failed to report latency metric: %v", err)
+		}
+	}
+
+	return resp, err
+}
+
+func (c *Client) retryOptions(req *http.Request) []retry.Option {
+	return []retry.Option{
+		retry.WithMaxAttempts(c.config.MaxRetries + 1),
+		retry.WithBackoff(retry.Exponential(
+			time.Duration(c.config.RetryBackoffMs)*time.Millisecond,
+			time.Duration(c.config.RetryMaxBackoffMs)*time.Millisecond,
+			true)),
+		retry.WithOnRetry(func(ctx context.Context, attempt retry.Attempt) {
+			logger.FromContext(ctx).WithFields(logger.Fields{
+				"client":  c.config.Name,
+				"method":  req.Method,
+				"host":    req.URL.Host,
+				"path":    req.URL.Path,
+				"attempt": attempt.Number,
+			}).Warnf("Retrying request in %v after failure: %v", attempt.Delay, attempt.Err)
+		}),
+	}
+}
+
+func (c *Client) isRetryable(req *http.Request) bool {
+	if !idempotentMethods[req.Method] {
+		return false
+	}
+	// The body can't be sent again if it can't be rewound
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+type statusError struct {
+	status int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("status %d", e.status)
+}
+
+func drain(resp *http.Response) {
+	_, _ = io.Copy(ioutil.Discard, io.LimitReader(resp.Body, maxErrorBodyBytes))
+	resp.Body.Close()
+}
@@ -0,0 +1,124 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/afex/hystrix-go/hystrix"
+	"github.com/callicoder/go-commons/circuitbreaker"
+	"github.com/callicoder/go-commons/errors"
+	"github.com/callicoder/go-commons/errors/codes"
+	"github.com/callicoder/go-commons/logger"
+	"github.com/callicoder/go-commons/requestutil"
+	"github.com/callicoder/go-commons/statsd/statsdtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClient(t *testing.T) {
+	logger.SetupRootLogger(logger.Config{Level: "panic"})
+
+	t.Run("should retry idempotent requests on transient failures", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if atomic.AddInt32(&calls, 1) < 3 {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			w.Write([]byte(`{"name":"sachin"}`))
+		}))
+		defer server.Close()
+
+		stats := statsdtest.NewClient()
+		client := New(Config{Name: "users", MaxRetries: 2, RetryBackoffMs: 1}, WithStatsd(stats))
+
+		var user struct{ Name string }
+		err := client.Get(context.Background(), server.URL, &user)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "sachin", user.Name)
+		assert.Equal(t, int32(3), calls)
+		stats.AssertCounter(t, metricRequests, 3)
+		stats.AssertTagged(t, metricRequests, "client:users", "method:GET", "status:503")
+	})
+
+	t.Run("should not retry non idempotent requests", func(t *testing.T) {
+		var calls int32
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&calls, 1)
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		client := New(Config{MaxRetries: 2, RetryBackoffMs: 1})
+		err := client.Post(context.Background(), server.URL, map[string]string{"name": "sachin"}, nil)
+
+		assert.Error(t, err)
+		assert.Equal(t, int32(1), calls)
+	})
+
+	t.Run("should decode downstream BaseErrors", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"code":"user_not_found","message":"User not found"}`))
+		}))
+		defer server.Close()
+
+		err := New(Config{}).Get(context.Background(), server.URL, nil)
+
+		assert.Equal(t, &errors.BaseError{Code: "user_not_found", Message: "User not found"}, err)
+	})
+
+	t.Run("should map statuses to codes when the body is not a BaseError", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusConflict)
+		}))
+		defer server.Close()
+
+		err := New(Config{}).Get(context.Background(), server.URL, nil)
+
+		baseErr, ok := err.(*errors.BaseError)
+		assert.True(t, ok)
+		assert.Equal(t, codes.Conflict, baseErr.Code)
+	})
+
+	t.Run("should propagate the request id and context headers", func(t *testing.T) {
+		var header http.Header
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			header = r.Header
+		}))
+		defer server.Close()
+
+		ctx := requestutil.WithRequestID(context.Background(), "abc-123")
+		ctx = WithHeaders(ctx, http.Header{"X-Tenant-Id": []string{"t1"}})
+		err := New(Config{CircuitBreaker: true}).Get(ctx, server.URL, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, "abc-123", header.Get(requestutil.HeaderRequestID))
+		assert.Equal(t, "t1", header.Get("X-Tenant-Id"))
+	})
+
+	t.Run("should apply the client timeout to the circuit breaker", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(1200 * time.Millisecond)
+		}))
+		defer server.Close()
+
+		client := New(Config{Name: "slow-service", CircuitBreaker: true})
+		err := client.Get(context.Background(), server.URL, nil)
+
+		assert.NoError(t, err)
+		assert.Equal(t, time.Duration(defaultTimeoutMs)*time.Millisecond, hystrix.GetCircuitSettings()["slow-service"].Timeout)
+	})
+
+	t.Run("should keep the circuit breaker configuration of the command", func(t *testing.T) {
+		circuitbreaker.ConfigureCommand("configured-service", circuitbreaker.CommandConfig{TimeoutMs: 500})
+
+		New(Config{Name: "configured-service", CircuitBreaker: true})
+
+		assert.Equal(t, 500*time.Millisecond, hystrix.GetCircuitSettings()["configured-service"].Timeout)
+	})
+}
@@ -0,0 +1,161 @@
+package lifecycle
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/callicoder/go-commons/logger"
+	"github.com/callicoder/go-commons/server"
+)
+
+const defaultGracefulShutdownTimeoutMs = 30000
+
+type Config struct {
+	// GracefulShutdownTimeoutMs bounds the time spent stopping all the components
+	GracefulShutdownTimeoutMs int `mapstructure:"graceful_shutdown_timeout_ms"`
+}
+
+// Hook attaches a component to the lifecycle of an App. All the fields are optional.
+type Hook struct {
+	Name    string
+	OnStart func(ctx context.Context) error
+	OnStop  func(ctx context.Context) error
+	// Err reports failures of the component once started, which stop the App
+	Err <-chan error
+}
+
+// App starts components in the order they were appended, and stops them in the
+// reverse order when it receives SIGINT or SIGTERM, its context is done or a component fails.
+type App struct {
+	hooks           []Hook
+	shutdownTimeout time.Duration
+	signals         []os.Signal
+}
+
+func New(c Config) *App {
+	timeoutMs := c.GracefulShutdownTimeoutMs
+	if timeoutMs <= 0 {
+		timeoutMs = defaultGracefulShutdownTimeoutMs
+	}
+	return &App{
+		shutdownTimeout: time.Duration(timeoutMs) * time.Millisecond,
+		signals:         []os.Signal{syscall.SIGINT, syscall.SIGTERM},
+	}
+}
+
+func (a *App) Append(hooks ...Hook) {
+	a.hooks = append(a.hooks, hooks...)
+}
+
+// Run starts all the components and blocks until the App is stopped. It returns the
+// error of the component which failed to start or stopped the App, if any, or else
+// the first error encountered while stopping the components.
+func (a *App) Run(ctx context.Context) error {
+	sigs := make(chan os.Signal, 1)
+	signal.Notify(sigs, a.signals...)
+	defer signal.Stop(sigs)
+
+	started, err := a.start(ctx)
+	if err != nil {
+		a.stop(started)
+		return err
+	}
+
+	// done stops watching the components for failures once Run returns
+	done := make(chan struct{})
+	defer close(done)
+	failures := make(chan error, len(a.hooks))
+	for _, hook := range a.hooks {
+		if hook.Err == nil {
+			continue
+		}
+		go func(hook Hook) {
+			select {
+			case err, ok := <-hook.Err:
+				if ok && err != nil {
+					failures <- fmt.Errorf("%w :: %s failed", err, hook.Name)
+				}
+			case <-done:
+			}
+		}(hook)
+	}
+
+	select {
+	case sig := <-sigs:
+		logger.Infof("Received signal %v, shutting down", sig)
+	case <-ctx.Done():
+		logger.Infof("Context done, shutting down")
+	case err = <-failures:
+		logger.Errorf("Shutting down after failure: %v", err)
+	}
+
+	if stopErr := a.stop(started); err == nil {
+		err = stopErr
+	}
+	return err
+}
+
+func (a *App) start(ctx context.Context) ([]Hook, error) {
+	started := make([]Hook, 0, len(a.hooks))
+	for _, hook := range a.hooks {
+		if hook.OnStart != nil {
+			logger.Infof("Starting %s", hook.Name)
+			if err := hook.OnStart(ctx); err != nil {
+				return started, fmt.Errorf("%w :: Failed to start %s", err, hook.Name)
+			}
+		}
+		started = append(started, hook)
+	}
+	return started, nil
+}
+
+func (a *App) stop(started []Hook) error {
+	ctx, cancel := context.WithTimeout(context.Background(), a.shutdownTimeout)
+	defer cancel()
+
+	var firstErr error
+	for i := len(started) - 1; i >= 0; i-- {
+		hook := started[i]
+		if hook.OnStop == nil {
+			continue
+		}
+		logger.Infof("Stopping %s", hook.Name)
+		if err := hook.OnStop(ctx); err != nil {
+			logger.Errorf("Failed to stop %s: %v", hook.Name, err)
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%w :: Failed to stop %s", err, hook.Name)
+			}
+		}
+	}
+	return firstErr
+}
+
+// HTTPServer hooks an http server to the App.
+func HTTPServer(s *server.Server) Hook {
+	return Hook{
+		Name: "http server",
+		OnStart: func(ctx context.Context) error {
+			return s.Start()
+		},
+		OnStop: func(ctx context.Context) error {
+			return s.ShutdownContext(ctx)
+		},
+		Err: s.Err(),
+	}
+}
+
+// Closer hooks a component which only needs to be closed on shutdown, such as a
+// db.SqlDB, a redis.Client or a statsd.Reporter.
+func Closer(name string, c io.Closer) Hook {
+	return Hook{
+		Name: name,
+		OnStop: func(ctx context.Context) error {
+			return c.Close()
+		},
+	}
+}
@@ -0,0 +1,96 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/callicoder/go-commons/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func recordingHook(name string, events *[]string, errs <-chan error) Hook {
+	return Hook{
+		Name: name,
+		OnStart: func(ctx context.Context) error {
+			*events = append(*events, "start "+name)
+			return nil
+		},
+		OnStop: func(ctx context.Context) error {
+			*events = append(*events, "stop "+name)
+			return nil
+		},
+		Err: errs,
+	}
+}
+
+func TestApp(t *testing.T) {
+	logger.SetupRootLogger(logger.Config{Level: "panic"})
+
+	t.Run("should stop components in reverse order when the context is done", func(t *testing.T) {
+		var events []string
+		app := New(Config{})
+		app.Append(recordingHook("db", &events, nil), recordingHook("server", &events, nil))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		assert.NoError(t, app.Run(ctx))
+		assert.Equal(t, []string{"start db", "start server", "stop server", "stop db"}, events)
+	})
+
+	t.Run("should stop watching components once stopped", func(t *testing.T) {
+		var events []string
+		app := New(Config{})
+		app.Append(recordingHook("server", &events, make(chan error)))
+
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		// The first run starts the signal handling goroutine of the runtime
+		assert.NoError(t, app.Run(ctx))
+		goroutines := runtime.NumGoroutine()
+		for i := 0; i < 10; i++ {
+			assert.NoError(t, app.Run(ctx))
+		}
+
+		// Polled inline, as assert.Eventually runs its condition in another goroutine
+		deadline := time.Now().Add(time.Second)
+		for runtime.NumGoroutine() > goroutines && time.Now().Before(deadline) {
+			time.Sleep(10 * time.Millisecond)
+		}
+		assert.LessOrEqual(t, runtime.NumGoroutine(), goroutines)
+	})
+
+	t.Run("should return the error of a failing component", func(t *testing.T) {
+		var events []string
+		errs := make(chan error, 1)
+		errs <- errors.New("serve failed")
+
+		app := New(Config{})
+		app.Append(recordingHook("db", &events, nil), recordingHook("server", &events, errs))
+
+		err := app.Run(context.Background())
+
+		assert.EqualError(t, err, "serve failed :: server failed")
+		assert.Equal(t, []string{"start db", "start server", "stop server", "stop db"}, events)
+	})
+
+	t.Run("should stop started components when a component fails to start", func(t *testing.T) {
+		var events []string
+		app := New(Config{})
+		app.Append(recordingHook("db", &events, nil), Hook{
+			Name: "server",
+			OnStart: func(ctx context.Context) error {
+				return errors.New("address in use")
+			},
+		})
+
+		err := app.Run(context.Background())
+
+		assert.EqualError(t, err, "address in use :: Failed to start server")
+		assert.Equal(t, []string{"start db", "stop db"}, events)
+	})
+}
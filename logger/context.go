@@ -0,0 +1,100 @@
+package logger
+
+import (
+	"context"
+
+	"github.com/callicoder/go-commons/requestutil"
+)
+
+const FieldRequestID = "request_id"
+
+type contextKey int
+
+const loggerKey contextKey = iota
+
+// NewContext returns a copy of ctx which carries l.
+func NewContext(ctx context.Context, l Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, l)
+}
+
+// WithContext returns a copy of ctx carrying the logger of ctx enriched with fields.
+func WithContext(ctx context.Context, fields Fields) context.Context {
+	return NewContext(ctx, FromContext(ctx).WithFields(fields))
+}
+
+// FromContext returns the logger stored in ctx, falling back to the root logger.
+// The request id stored in ctx, if any, is attached to the returned logger.
+func FromContext(ctx context.Context) Logger {
+	var l Logger
+	if ctx != nil {
+		l, _ = ctx.Value(loggerKey).(Logger)
+	}
+	if l == nil {
+		l = WithFields(nil)
+	}
+
+	if requestID := requestutil.RequestID(ctx); requestID != "" {
+		return l.WithFields(Fields{FieldRequestID: requestID})
+	}
+	return l
+}
+
+// FromContextOrStd is FromContext for the packages which do not require SetupRootLogger.
+// When ctx carries no logger and the root logger is not set up, the returned logger
+// writes to the standard library logger, with the request id stored in ctx attached.
+func FromContextOrStd(ctx context.Context) Logger {
+	if Initialized() {
+		return FromContext(ctx)
+	}
+	if ctx != nil {
+		if l, _ := ctx.Value(loggerKey).(Logger); l != nil {
+			return FromContext(ctx)
+		}
+	}
+
+	var l Logger = &stdLogger{}
+	if requestID := requestutil.RequestID(ctx); requestID != "" {
+		l = l.WithFields(Fields{FieldRequestID: requestID})
+	}
+	return l
+}
+
+func DebugfContext(ctx context.Context, format string, args ...interface{}) {
+	FromContext(ctx).Debugf(format, args...)
+}
+
+func InfofContext(ctx context.Context, format string, args ...interface{}) {
+	FromContext(ctx).Infof(format, args...)
+}
+
+func WarnfContext(ctx context.Context, format string, args ...interface{}) {
+	FromContext(ctx).Warnf(format, args...)
+}
+
+func ErrorfContext(ctx context.Context, format string, args ...interface{}) {
+	FromContext(ctx).Errorf(format, args...)
+}
+
+func FatalfContext(ctx context.Context, format string, args ...interface{}) {
+	FromContext(ctx).Fatalf(format, args...)
+}
+
+func DebugContext(ctx context.Context, args ...interface{}) {
+	FromContext(ctx).Debug(args...)
+}
+
+func InfoContext(ctx context.Context, args ...interface{}) {
+	FromContext(ctx).Info(args...)
+}
+
+func WarnContext(ctx context.Context, args ...interface{}) {
+	FromContext(ctx).Warn(args...)
+}
+
+func ErrorContext(ctx context.Context, args ...interface{}) {
+	FromContext(ctx).Error(args...)
+}
+
+func FatalContext(ctx context.Context, args ...interface{}) {
+	FromContext(ctx).Fatal(args...)
+}
@@ -0,0 +1,92 @@
+package logger
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"os"
+	"testing"
+
+	"github.com/callicoder/go-commons/requestutil"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func setupBufferedRootLogger() *bytes.Buffer {
+	buf := &bytes.Buffer{}
+	rootLogger = &logrusLogger{&logrus.Logger{
+		Out:       buf,
+		Hooks:     make(logrus.LevelHooks),
+		Level:     logrus.InfoLevel,
+		Formatter: &logrus.JSONFormatter{},
+	}}
+	return buf
+}
+
+func decodeEntry(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	entry := map[string]interface{}{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	buf.Reset()
+	return entry
+}
+
+func TestFromContext(t *testing.T) {
+	buf := setupBufferedRootLogger()
+
+	t.Run("should fall back to the root logger", func(t *testing.T) {
+		InfofContext(context.Background(), "hello %s", "world")
+
+		entry := decodeEntry(t, buf)
+		assert.Equal(t, "hello world", entry["msg"])
+		assert.NotContains(t, entry, FieldRequestID)
+	})
+
+	t.Run("should attach fields and request id from the context", func(t *testing.T) {
+		ctx := requestutil.WithRequestID(context.Background(), "abc-123")
+		ctx = WithContext(ctx, Fields{"user": "u1"})
+		ctx = WithContext(ctx, Fields{"tenant": "t1"})
+
+		ErrorContext(ctx, "failed")
+
+		entry := decodeEntry(t, buf)
+		assert.Equal(t, "failed", entry["msg"])
+		assert.Equal(t, "abc-123", entry[FieldRequestID])
+		assert.Equal(t, "u1", entry["user"])
+		assert.Equal(t, "t1", entry["tenant"])
+	})
+}
+
+func TestFromContextOrStd(t *testing.T) {
+	root := rootLogger
+	defer func() { rootLogger = root }()
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	}()
+
+	t.Run("should write to the standard logger without a root logger", func(t *testing.T) {
+		rootLogger = nil
+		ctx := requestutil.WithRequestID(context.Background(), "abc-123")
+
+		FromContextOrStd(ctx).WithFields(Fields{"query": "SELECT 1"}).Warnf("query %s", "failed")
+		FromContextOrStd(nil).Debugf("dropped")
+
+		assert.Equal(t, "WARN query failed query=SELECT 1 request_id=abc-123\n", out.String())
+		out.Reset()
+	})
+
+	t.Run("should use the root logger once set up", func(t *testing.T) {
+		buf := setupBufferedRootLogger()
+
+		FromContextOrStd(requestutil.WithRequestID(context.Background(), "abc-123")).Infof("hello")
+
+		entry := decodeEntry(t, buf)
+		assert.Equal(t, "hello", entry["msg"])
+		assert.Equal(t, "abc-123", entry[FieldRequestID])
+		assert.Empty(t, out.String())
+	})
+}
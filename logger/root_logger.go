@@ -6,6 +6,11 @@ func SetupRootLogger(c Config) {
 	rootLogger = newLogrusLogger(c)
 }
 
+// Initialized reports whether the root logger was set up with SetupRootLogger.
+func Initialized() bool {
+	return rootLogger != nil
+}
+
 func WithFields(fields Fields) Logger {
 	if rootLogger == nil {
 		panic("Logger not initialized")
@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+)
+
+// stdLogger writes to the standard library logger. Debug messages are dropped, as with the
+// default level of the root logger.
+type stdLogger struct {
+	fields Fields
+}
+
+func (l *stdLogger) print(level string, msg string) {
+	var buf strings.Builder
+	buf.WriteString(level)
+	buf.WriteString(" ")
+	buf.WriteString(msg)
+
+	keys := make([]string, 0, len(l.fields))
+	for key := range l.fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(&buf, " %s=%v", key, l.fields[key])
+	}
+
+	if level == "FATAL" {
+		log.Fatal(buf.String())
+	}
+	log.Print(buf.String())
+}
+
+func (l *stdLogger) Debug(args ...interface{}) {}
+
+func (l *stdLogger) Info(args ...interface{}) {
+	l.print("INFO", fmt.Sprint(args...))
+}
+
+func (l *stdLogger) Warn(args ...interface{}) {
+	l.print("WARN", fmt.Sprint(args...))
+}
+
+func (l *stdLogger) Error(args ...interface{}) {
+	l.print("ERROR", fmt.Sprint(args...))
+}
+
+func (l *stdLogger) Fatal(args ...interface{}) {
+	l.print("FATAL", fmt.Sprint(args...))
+}
+
+func (l *stdLogger) Debugf(format string, args ...interface{}) {}
+
+func (l *stdLogger) Infof(format string, args ...interface{}) {
+	l.print("INFO", fmt.Sprintf(format, args...))
+}
+
+func (l *stdLogger) Warnf(format string, args ...interface{}) {
+	l.print("WARN", fmt.Sprintf(format, args...))
+}
+
+func (l *stdLogger) Errorf(format string, args ...interface{}) {
+	l.print("ERROR", fmt.Sprintf(format, args...))
+}
+
+func (l *stdLogger) Fatalf(format string, args ...interface{}) {
+	l.print("FATAL", fmt.Sprintf(format, args...))
+}
+
+func (l *stdLogger) WithFields(fields Fields) Logger {
+	merged := Fields{}
+	for key, val := range l.fields {
+		merged[key] = val
+	}
+	for key, val := range fields {
+		merged[key] = val
+	}
+	return &stdLogger{fields: merged}
+}
@@ -7,6 +7,7 @@ import (
 
 	"errors"
 
+	"github.com/callicoder/go-commons/logger"
 	"github.com/go-redis/redis/v8"
 )
 
@@ -67,6 +68,7 @@ func NewClient(c Config) (Client, error) {
 		if err := r.Ping(ctx).Err(); err != nil {
 			return nil, errConnecting
 		}
+		r.AddHook(logHook{})
 		return r, nil
 	}
 
@@ -84,10 +86,44 @@ func NewClient(c Config) (Client, error) {
 	if err := r.Ping(ctx).Err(); err != nil {
 		return nil, errConnecting
 	}
+	r.AddHook(logHook{})
 
 	return r, nil
 }
 
+// logHook logs failed commands with the logger of their context, so that they can be
+// joined with the other logs of the request. Cache misses and cancelled requests are not
+// logged.
+type logHook struct{}
+
+func (logHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (logHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	logCommandError(ctx, cmd)
+	return nil
+}
+
+func (logHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	return ctx, nil
+}
+
+func (logHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	for _, cmd := range cmds {
+		logCommandError(ctx, cmd)
+	}
+	return nil
+}
+
+func logCommandError(ctx context.Context, cmd redis.Cmder) {
+	err := cmd.Err()
+	if err == nil || err == redis.Nil || errors.Is(err, context.Canceled) {
+		return
+	}
+	logger.FromContextOrStd(ctx).WithFields(logger.Fields{"command": cmd.Name()}).Warnf("Redis command failed: %v", err)
+}
+
 // Single client
 func (r *client) SetStruct(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
 	jsonData, err := json.Marshal(value)
@@ -1,12 +1,17 @@
 package redis
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"log"
 	"os"
 	"strings"
 	"testing"
 
+	"github.com/callicoder/go-commons/requestutil"
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/suite"
 )
 
@@ -106,3 +111,25 @@ func (suite *RedisTestSuite) TestHSetAndHGet() {
 func TestRedis(t *testing.T) {
 	suite.Run(t, new(RedisTestSuite))
 }
+
+func TestLogHook(t *testing.T) {
+	var out bytes.Buffer
+	log.SetOutput(&out)
+	log.SetFlags(0)
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+	}()
+	ctx := requestutil.WithRequestID(context.Background(), "abc-123")
+
+	failed := redis.NewStringCmd(ctx, "get", "user")
+	failed.SetErr(errors.New("connection reset"))
+	missed := redis.NewStringCmd(ctx, "get", "session")
+	missed.SetErr(redis.Nil)
+	succeeded := redis.NewStatusCmd(ctx, "set", "user", "sachin")
+
+	assert.NoError(t, logHook{}.AfterProcess(ctx, failed))
+	assert.NoError(t, logHook{}.AfterProcessPipeline(ctx, []redis.Cmder{missed, succeeded}))
+
+	assert.Equal(t, "WARN Redis command failed: connection reset command=get request_id=abc-123\n", out.String())
+}
@@ -0,0 +1,68 @@
+package requestutil
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+)
+
+const HeaderRequestID = "X-Request-ID"
+
+// maxRequestIDLength guards against clients sending arbitrarily large ids
+const maxRequestIDLength = 128
+
+type contextKey int
+
+const requestIDKey contextKey = iota
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+// RequestID returns the request id stored in ctx, or an empty string if there is none.
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// GetRequestID returns the request id sent by the client, if it is a valid one.
+func GetRequestID(r *http.Request) string {
+	requestID := r.Header.Get(HeaderRequestID)
+	if !isValidRequestID(requestID) {
+		return ""
+	}
+	return requestID
+}
+
+// PropagateRequestID sets the request id stored in ctx on an outbound request.
+func PropagateRequestID(ctx context.Context, req *http.Request) {
+	requestID := RequestID(ctx)
+	if requestID == "" || req.Header.Get(HeaderRequestID) != "" {
+		return
+	}
+	req.Header.Set(HeaderRequestID, requestID)
+}
+
+func isValidRequestID(requestID string) bool {
+	if len(requestID) == 0 || len(requestID) > maxRequestIDLength {
+		return false
+	}
+	for i := 0; i < len(requestID); i++ {
+		if c := requestID[i]; c < '!' || c > '~' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package retry
+
+import (
+	"math/rand"
+	"time"
+)
+
+// Backoff computes the delay to wait before a retry.
+// attempt is the number of attempts made so far, starting at 1, and prev is the previous delay.
+type Backoff interface {
+	Delay(attempt int, prev time.Duration) time.Duration
+}
+
+type BackoffFunc func(attempt int, prev time.Duration) time.Duration
+
+func (f BackoffFunc) Delay(attempt int, prev time.Duration) time.Duration {
+	return f(attempt, prev)
+}
+
+// Constant waits for the same delay between every attempt.
+func Constant(delay time.Duration) Backoff {
+	return BackoffFunc(func(attempt int, prev time.Duration) time.Duration {
+		return delay
+	})
+}
+
+// Exponential doubles the delay after every attempt, starting at base and capped at max.
+// With jitter, the actual delay is picked at random between 0 and the exponential delay.
+func Exponential(base, max time.Duration, jitter bool) Backoff {
+	return BackoffFunc(func(attempt int, prev time.Duration) time.Duration {
+		delay := base
+		for i := 1; i < attempt && delay < max; i++ {
+			delay *= 2
+		}
+		if delay > max {
+			delay = max
+		}
+		if jitter {
+			return randomBetween(0, delay)
+		}
+		return delay
+	})
+}
+
+// DecorrelatedJitter picks the delay at random between base and three times the
+// previous delay, capped at max.
+func DecorrelatedJitter(base, max time.Duration) Backoff {
+	return BackoffFunc(func(attempt int, prev time.Duration) time.Duration {
+		if prev < base {
+			prev = base
+		}
+		delay := randomBetween(base, prev*3)
+		if delay > max {
+			delay = max
+		}
+		return delay
+	})
+}
+
+func randomBetween(min, max time.Duration) time.Duration {
+	if max <= min {
+		return min
+	}
+	return min + time.Duration(rand.Int63n(int64(max-min)+1))
+}
@@ -0,0 +1,201 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/callicoder/go-commons/logger"
+	"github.com/callicoder/go-commons/statsd"
+)
+
+const (
+	PolicyConstant           = "constant"
+	PolicyExponential        = "exponential"
+	PolicyDecorrelatedJitter = "decorrelated_jitter"
+)
+
+const (
+	defaultMaxAttempts = 3
+	defaultBaseDelayMs = 100
+	defaultMaxDelayMs  = 5000
+)
+
+type Config struct {
+	// MaxAttempts is the total number of attempts, including the first one. Defaults to 3.
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// MaxElapsedTimeMs stops retrying once exceeded. Zero means no limit.
+	MaxElapsedTimeMs int `mapstructure:"max_elapsed_time_ms"`
+	// Policy is one of constant, exponential (default) or decorrelated_jitter
+	Policy      string
+	BaseDelayMs int `mapstructure:"base_delay_ms"`
+	MaxDelayMs  int `mapstructure:"max_delay_ms"`
+}
+
+// Attempt describes a failed attempt which is about to be retried.
+type Attempt struct {
+	// Number of the failed attempt, starting at 1
+	Number  int
+	Err     error
+	Delay   time.Duration
+	Elapsed time.Duration
+}
+
+type Retrier struct {
+	backoff     Backoff
+	maxAttempts int
+	maxElapsed  time.Duration
+	retryable   func(err error) bool
+	onRetry     []func(ctx context.Context, attempt Attempt)
+}
+
+type Option func(*Retrier)
+
+func WithBackoff(backoff Backoff) Option {
+	return func(r *Retrier) {
+		r.backoff = backoff
+	}
+}
+
+func WithMaxAttempts(maxAttempts int) Option {
+	return func(r *Retrier) {
+		r.maxAttempts = maxAttempts
+	}
+}
+
+func WithMaxElapsedTime(maxElapsed time.Duration) Option {
+	return func(r *Retrier) {
+		r.maxElapsed = maxElapsed
+	}
+}
+
+// WithRetryable sets the classifier deciding which errors are retried.
+// By default every error is retried, except the ones marked with Permanent.
+func WithRetryable(retryable func(err error) bool) Option {
+	return func(r *Retrier) {
+		r.retryable = retryable
+	}
+}
+
+// WithOnRetry adds a callback called before waiting for every retry.
+func WithOnRetry(onRetry func(ctx context.Context, attempt Attempt)) Option {
+	return func(r *Retrier) {
+		r.onRetry = append(r.onRetry, onRetry)
+	}
+}
+
+func New(opts ...Option) *Retrier {
+	r := &Retrier{
+		backoff:     Exponential(defaultBaseDelayMs*time.Millisecond, defaultMaxDelayMs*time.Millisecond, true),
+		maxAttempts: defaultMaxAttempts,
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+func NewFromConfig(c Config, opts ...Option) (*Retrier, error) {
+	base := time.Duration(c.BaseDelayMs) * time.Millisecond
+	if base <= 0 {
+		base = defaultBaseDelayMs * time.Millisecond
+	}
+	max := time.Duration(c.MaxDelayMs) * time.Millisecond
+	if max <= 0 {
+		max = defaultMaxDelayMs * time.Millisecond
+	}
+
+	var backoff Backoff
+	switch c.Policy {
+	case PolicyConstant:
+		backoff = Constant(base)
+	case "", PolicyExponential:
+		backoff = Exponential(base, max, true)
+	case PolicyDecorrelatedJitter:
+		backoff = DecorrelatedJitter(base, max)
+	default:
+		return nil, fmt.Errorf("unknown retry policy %q", c.Policy)
+	}
+
+	configOpts := []Option{WithBackoff(backoff)}
+	if c.MaxAttempts > 0 {
+		configOpts = append(configOpts, WithMaxAttempts(c.MaxAttempts))
+	}
+	if c.MaxElapsedTimeMs > 0 {
+		configOpts = append(configOpts, WithMaxElapsedTime(time.Duration(c.MaxElapsedTimeMs)*time.Millisecond))
+	}
+	return New(append(configOpts, opts...)...), nil
+}
+
+// Do calls fn until it succeeds, returns a non retryable error, the attempts or the
+// elapsed time are exhausted, or ctx is done. The error of the last attempt is returned.
+func Do(ctx context.Context, fn func(ctx context.Context) error, opts ...Option) error {
+	return New(opts...).Do(ctx, fn)
+}
+
+func (r *Retrier) Do(ctx context.Context, fn func(ctx context.Context) error) error {
+	start := time.Now()
+	var delay time.Duration
+
+	for attempt := 1; ; attempt++ {
+		err := fn(ctx)
+		if err == nil {
+			return nil
+		}
+		if !r.shouldRetry(err) {
+			return unwrapPermanent(err)
+		}
+		if r.maxAttempts > 0 && attempt >= r.maxAttempts {
+			return err
+		}
+
+		delay = r.backoff.Delay(attempt, delay)
+		elapsed := time.Since(start)
+		if r.maxElapsed > 0 && elapsed+delay > r.maxElapsed {
+			return err
+		}
+
+		for _, onRetry := range r.onRetry {
+			onRetry(ctx, Attempt{Number: attempt, Err: err, Delay: delay, Elapsed: elapsed})
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		}
+	}
+}
+
+func (r *Retrier) shouldRetry(err error) bool {
+	if isPermanent(err) {
+		return false
+	}
+	if r.retryable != nil {
+		return r.retryable(err)
+	}
+	return true
+}
+
+// LogAttempts logs every retry of the operation name through the context logger, or the
+// standard logger when the root logger is not set up.
+func LogAttempts(name string) func(ctx context.Context, attempt Attempt) {
+	return func(ctx context.Context, attempt Attempt) {
+		logger.FromContextOrStd(ctx).WithFields(logger.Fields{
+			"operation": name,
+			"attempt":   attempt.Number,
+			"delay_ms":  float64(attempt.Delay) / float64(time.Millisecond),
+		}).Warnf("Retrying %s after failure: %v", name, attempt.Err)
+	}
+}
+
+// CountAttempts increments the retry.attempts metric, tagged with the operation name, on every retry.
+func CountAttempts(client statsd.Client, name string) func(ctx context.Context, attempt Attempt) {
+	return func(ctx context.Context, attempt Attempt) {
+		if err := client.IncrementWithTags("retry.attempts", "operation:"+name); err != nil {
+			logger.Errorf("Failed to report retry metric: %v", err)
+		}
+	}
+}
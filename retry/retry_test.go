@@ -0,0 +1,100 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errTransient = errors.New("transient")
+
+func TestDo(t *testing.T) {
+	t.Run("should retry until success", func(t *testing.T) {
+		calls := 0
+		var attempts []Attempt
+		err := Do(context.Background(), func(ctx context.Context) error {
+			calls++
+			if calls < 3 {
+				return errTransient
+			}
+			return nil
+		}, WithBackoff(Constant(time.Millisecond)), WithOnRetry(func(ctx context.Context, attempt Attempt) {
+			attempts = append(attempts, attempt)
+		}))
+
+		assert.NoError(t, err)
+		assert.Equal(t, 3, calls)
+		assert.Len(t, attempts, 2)
+		assert.Equal(t, 2, attempts[1].Number)
+	})
+
+	t.Run("should stop after max attempts", func(t *testing.T) {
+		calls := 0
+		err := Do(context.Background(), func(ctx context.Context) error {
+			calls++
+			return errTransient
+		}, WithBackoff(Constant(time.Millisecond)), WithMaxAttempts(4))
+
+		assert.Equal(t, errTransient, err)
+		assert.Equal(t, 4, calls)
+	})
+
+	t.Run("should not retry permanent or non retryable errors", func(t *testing.T) {
+		errFatal := errors.New("fatal")
+		calls := 0
+		err := Do(context.Background(), func(ctx context.Context) error {
+			calls++
+			return Permanent(errFatal)
+		})
+		assert.Equal(t, errFatal, err)
+		assert.Equal(t, 1, calls)
+
+		calls = 0
+		err = Do(context.Background(), func(ctx context.Context) error {
+			calls++
+			return errFatal
+		}, WithRetryable(func(err error) bool {
+			return err == errTransient
+		}))
+		assert.Equal(t, errFatal, err)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("should stop when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		calls := 0
+		err := Do(ctx, func(ctx context.Context) error {
+			calls++
+			return errTransient
+		}, WithBackoff(Constant(time.Hour)))
+
+		assert.Equal(t, errTransient, err)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	t.Run("exponential backoff should double up to max", func(t *testing.T) {
+		backoff := Exponential(10*time.Millisecond, 50*time.Millisecond, false)
+
+		assert.Equal(t, 10*time.Millisecond, backoff.Delay(1, 0))
+		assert.Equal(t, 20*time.Millisecond, backoff.Delay(2, 0))
+		assert.Equal(t, 40*time.Millisecond, backoff.Delay(3, 0))
+		assert.Equal(t, 50*time.Millisecond, backoff.Delay(4, 0))
+	})
+
+	t.Run("decorrelated jitter should stay within bounds", func(t *testing.T) {
+		backoff := DecorrelatedJitter(10*time.Millisecond, 100*time.Millisecond)
+
+		var delay time.Duration
+		for attempt := 1; attempt < 20; attempt++ {
+			delay = backoff.Delay(attempt, delay)
+			assert.True(t, delay >= 10*time.Millisecond && delay <= 100*time.Millisecond, delay)
+		}
+	})
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"time"
+
+	"github.com/callicoder/go-commons/handler/response"
+)
+
+// AdminConfig configures a second listener serving operational endpoints:
+// /health/live, /health/ready, /info, /metrics and /debug/pprof.
+type AdminConfig struct {
+	// Host defaults to the host of the server
+	Host string
+	// Port enables the admin listener when set
+	Port int
+	// Pprof enables the /debug/pprof endpoints
+	Pprof bool
+}
+
+func (c AdminConfig) Enabled() bool {
+	return c.Port > 0
+}
+
+// BuildInfo describes the running build on the /info endpoint, usually set with -ldflags.
+type BuildInfo struct {
+	Version   string `json:"version,omitempty"`
+	Commit    string `json:"commit,omitempty"`
+	BuildTime string `json:"build_time,omitempty"`
+}
+
+type buildInfoResponse struct {
+	BuildInfo
+	GoVersion string `json:"go_version"`
+	Module    string `json:"module,omitempty"`
+	StartTime string `json:"start_time"`
+}
+
+func (s *Server) newAdminServer(o *options) *http.Server {
+	mux := http.NewServeMux()
+
+	liveness := o.liveness
+	if liveness == nil {
+		liveness = http.HandlerFunc(okHandler)
+	}
+	readiness := o.readiness
+	if readiness == nil {
+		readiness = http.HandlerFunc(okHandler)
+	}
+	mux.Handle("/health/live", liveness)
+	mux.Handle("/health/ready", s.notReadyWhenShuttingDown(readiness))
+	mux.Handle("/info", buildInfoHandler(o.buildInfo, time.Now()))
+
+	metrics := o.metrics
+	if metrics == nil {
+		if scraped, ok := o.stats.(interface{ Handler() http.Handler }); ok {
+			metrics = scraped.Handler()
+		}
+	}
+	if metrics != nil {
+		mux.Handle("/metrics", metrics)
+	}
+
+	if s.config.Admin.Pprof {
+		mux.HandleFunc("/debug/pprof/", pprof.Index)
+		mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+		mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+		mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+		mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	}
+
+	for pattern, h := range o.adminHandlers {
+		mux.Handle(pattern, h)
+	}
+
+	host := s.config.Admin.Host
+	if host == "" {
+		host = s.config.Host
+	}
+	if host == "" {
+		host = defaultHost
+	}
+
+	return &http.Server{
+		Handler:           mux,
+		Addr:              net.JoinHostPort(host, strconv.Itoa(s.config.Admin.Port)),
+		ReadHeaderTimeout: time.Duration(s.config.ReadHeaderTimeoutMs) * time.Millisecond,
+		IdleTimeout:       time.Duration(s.config.IdleTimeoutMs) * time.Millisecond,
+	}
+}
+
+// notReadyWhenShuttingDown fails readiness checks once the server is shutting down,
+// so that load balancers stop routing traffic to it
+func (s *Server) notReadyWhenShuttingDown(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-s.shuttingDown:
+			http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		default:
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+func okHandler(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write([]byte("ok"))
+}
+
+func buildInfoHandler(info BuildInfo, startTime time.Time) http.Handler {
+	body := buildInfoResponse{
+		BuildInfo: info,
+		GoVersion: runtime.Version(),
+		StartTime: startTime.UTC().Format(time.RFC3339),
+	}
+	if moduleInfo, ok := debug.ReadBuildInfo(); ok {
+		body.Module = moduleInfo.Main.Path
+		if body.Version == "" {
+			body.Version = moduleInfo.Main.Version
+		}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		response.JSON(w, http.StatusOK, body)
+	})
+}
@@ -1,17 +1,61 @@
 package server
 
+import (
+	"net"
+	"strconv"
+
+	"github.com/callicoder/go-commons/server/middleware"
+)
+
+const defaultHost = "0.0.0.0"
+
 type Config struct {
-	ContextPath               string
+	// ContextPath mounts the handler under a path prefix, e.g. /api, which is
+	// stripped from the request path before the middlewares. Requests outside of it get
+	// a 404, unless they match a handler set with WithHandler.
+	ContextPath string
+	// Host is the address to bind to, defaults to 0.0.0.0
+	Host                      string
 	Port                      int
 	ReadTimeoutMs             int `mapstructure:"read_timeout_ms"`
+	ReadHeaderTimeoutMs       int `mapstructure:"read_header_timeout_ms"`
 	WriteTimeoutMs            int `mapstructure:"write_timeout_ms"`
+	IdleTimeoutMs             int `mapstructure:"idle_timeout_ms"`
+	MaxHeaderBytes            int `mapstructure:"max_header_bytes"`
 	GracefulShutdownTimeoutMs int `mapstructure:"graceful_shutdown_timeout_ms"`
-	CORS                      CORSConfig
+	TLS                       TLSConfig
+	Admin                     AdminConfig
+	// Middlewares lists the built-in middlewares to enable, outermost first.
+	// Defaults to middleware.DefaultEnabled when empty.
+	Middlewares []string
+	CORS        CORSConfig
+	AccessLog   AccessLogConfig `mapstructure:"access_log"`
+	Metrics     MetricsConfig
+	BodyLimit   BodyLimitConfig `mapstructure:"body_limit"`
+}
+
+type CORSConfig = middleware.CORSConfig
+
+type AccessLogConfig = middleware.AccessLogConfig
+
+type MetricsConfig = middleware.MetricsConfig
+
+type BodyLimitConfig = middleware.BodyLimitConfig
+
+func (c Config) addr() string {
+	host := c.Host
+	if host == "" {
+		host = defaultHost
+	}
+	return net.JoinHostPort(host, strconv.Itoa(c.Port))
 }
 
-type CORSConfig struct {
-	AllowedOrigins []string `mapstructure:"allowed_origins"`
-	AllowedHeaders []string `mapstructure:"allowed_headers"`
-	AllowedMethods []string `mapstructure:"allowed_methods"`
-	MaxAge         int      `mapstructure:"maxage"`
+func (c Config) middlewareConfig() middleware.Config {
+	return middleware.Config{
+		Enabled:   c.Middlewares,
+		CORS:      c.CORS,
+		AccessLog: c.AccessLog,
+		Metrics:   c.Metrics,
+		BodyLimit: c.BodyLimit,
+	}
 }
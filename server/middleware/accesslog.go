@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"math/rand"
+	"net/http"
+	"time"
+
+	"github.com/callicoder/go-commons/logger"
+	"github.com/callicoder/go-commons/requestutil"
+	"github.com/callicoder/go-commons/sliceutil"
+)
+
+type AccessLogConfig struct {
+	// SuccessSampleRate is the fraction of 2xx requests to log, between 0 and 1.
+	// Zero disables sampling, i.e. every request is logged.
+	// Requests with any other status are always logged.
+	SuccessSampleRate float64 `mapstructure:"success_sample_rate"`
+	// SkipPaths lists request paths which are never logged, e.g. /ping
+	SkipPaths []string `mapstructure:"skip_paths"`
+	// Routes lists route templates such as /users/:id used for the route field, see
+	// NewRouteTemplater
+	Routes []string
+}
+
+// AccessLog logs one structured line per request with its status, size and latency.
+func AccessLog(conf AccessLogConfig) Middleware {
+	templater := NewRouteTemplater(conf.Routes)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if sliceutil.Contains(conf.SkipPaths, r.URL.Path) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			start := time.Now()
+			rw := WrapResponseWriter(w)
+			next.ServeHTTP(rw, r)
+			latency := time.Since(start)
+
+			status := rw.Status()
+			if isSuccess(status) && !sampled(conf.SuccessSampleRate) {
+				return
+			}
+
+			requestID := requestutil.RequestID(r.Context())
+			if requestID == "" {
+				// The request id middleware may run after this one
+				requestID = rw.Header().Get(requestutil.HeaderRequestID)
+			}
+
+			fields := logger.Fields{
+				"method":     r.Method,
+				"path":       r.URL.Path,
+				"route":      templater.TemplateResponse(r.URL.Path, status),
+				"status":     status,
+				"bytes":      rw.BytesWritten(),
+				"latency_ms": float64(latency) / float64(time.Millisecond),
+				"client_ip":  requestutil.GetIpAddress(r).String(),
+				"user_agent": r.UserAgent(),
+			}
+			if requestID != "" {
+				fields[logger.FieldRequestID] = requestID
+			}
+
+			l := logger.FromContext(r.Context()).WithFields(fields)
+			switch {
+			case status >= http.StatusInternalServerError:
+				l.Errorf("%s %s %d", r.Method, r.URL.Path, status)
+			case status >= http.StatusBadRequest:
+				l.Warnf("%s %s %d", r.Method, r.URL.Path, status)
+			default:
+				l.Infof("%s %s %d", r.Method, r.URL.Path, status)
+			}
+		})
+	}
+}
+
+func isSuccess(status int) bool {
+	return status >= 200 && status < 300
+}
+
+func sampled(rate float64) bool {
+	if rate <= 0 || rate >= 1 {
+		return true
+	}
+	return rand.Float64() < rate
+}
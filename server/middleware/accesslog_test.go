@@ -0,0 +1,137 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/callicoder/go-commons/logger"
+	"github.com/callicoder/go-commons/requestutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type logEntry struct {
+	level   string
+	message string
+	fields  logger.Fields
+}
+
+// recordingLogger is a logger.Logger keeping every entry logged with the f variants
+type recordingLogger struct {
+	mu      *sync.Mutex
+	entries *[]logEntry
+	fields  logger.Fields
+}
+
+func newRecordingLogger() *recordingLogger {
+	return &recordingLogger{mu: &sync.Mutex{}, entries: &[]logEntry{}, fields: logger.Fields{}}
+}
+
+func (l *recordingLogger) log(level, format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	*l.entries = append(*l.entries, logEntry{level: level, message: fmt.Sprintf(format, args...), fields: l.fields})
+}
+
+func (l *recordingLogger) Entries() []logEntry {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return append([]logEntry(nil), *l.entries...)
+}
+
+func (l *recordingLogger) Debug(args ...interface{}) {}
+func (l *recordingLogger) Info(args ...interface{})  {}
+func (l *recordingLogger) Warn(args ...interface{})  {}
+func (l *recordingLogger) Error(args ...interface{}) {}
+func (l *recordingLogger) Fatal(args ...interface{}) {}
+
+func (l *recordingLogger) Debugf(format string, args ...interface{}) { l.log("debug", format, args...) }
+func (l *recordingLogger) Infof(format string, args ...interface{})  { l.log("info", format, args...) }
+func (l *recordingLogger) Warnf(format string, args ...interface{})  { l.log("warn", format, args...) }
+func (l *recordingLogger) Errorf(format string, args ...interface{}) { l.log("error", format, args...) }
+func (l *recordingLogger) Fatalf(format string, args ...interface{}) { l.log("fatal", format, args...) }
+
+func (l *recordingLogger) WithFields(fields logger.Fields) logger.Logger {
+	merged := logger.Fields{}
+	for k, v := range l.fields {
+		merged[k] = v
+	}
+	for k, v := range fields {
+		merged[k] = v
+	}
+	return &recordingLogger{mu: l.mu, entries: l.entries, fields: merged}
+}
+
+func TestAccessLog(t *testing.T) {
+	serve := func(conf AccessLogConfig, h http.HandlerFunc, path string) []logEntry {
+		l := newRecordingLogger()
+		r := httptest.NewRequest(http.MethodPost, path, nil)
+		r.Header.Set("User-Agent", "go-test")
+		r = r.WithContext(logger.NewContext(r.Context(), l))
+
+		AccessLog(conf)(h).ServeHTTP(httptest.NewRecorder(), r)
+		return l.Entries()
+	}
+	respond := func(status int, body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+			_, _ = w.Write([]byte(body))
+		}
+	}
+
+	t.Run("should log the status, size and route of the request", func(t *testing.T) {
+		entries := serve(AccessLogConfig{Routes: []string{"/users/:name"}}, respond(http.StatusCreated, "hello"), "/users/sachin")
+
+		require.Len(t, entries, 1)
+		fields := entries[0].fields
+		assert.Equal(t, "info", entries[0].level)
+		assert.Equal(t, "POST /users/sachin 201", entries[0].message)
+		assert.Equal(t, http.MethodPost, fields["method"])
+		assert.Equal(t, "/users/sachin", fields["path"])
+		assert.Equal(t, "/users/:name", fields["route"])
+		assert.Equal(t, http.StatusCreated, fields["status"])
+		assert.Equal(t, 5, fields["bytes"])
+		assert.Equal(t, "go-test", fields["user_agent"])
+		assert.Contains(t, fields, "latency_ms")
+		assert.Contains(t, fields, "client_ip")
+	})
+
+	t.Run("should log at a level depending on the status class", func(t *testing.T) {
+		for status, level := range map[int]string{
+			http.StatusOK:                  "info",
+			http.StatusNotFound:            "warn",
+			http.StatusServiceUnavailable:  "error",
+			http.StatusInternalServerError: "error",
+		} {
+			entries := serve(AccessLogConfig{}, respond(status, ""), "/")
+			require.Len(t, entries, 1)
+			assert.Equal(t, level, entries[0].level, "status %d", status)
+		}
+	})
+
+	t.Run("should not log skipped paths", func(t *testing.T) {
+		entries := serve(AccessLogConfig{SkipPaths: []string{"/ping"}}, respond(http.StatusOK, "pong"), "/ping")
+
+		assert.Empty(t, entries)
+	})
+
+	t.Run("should sample successful requests only", func(t *testing.T) {
+		conf := AccessLogConfig{SuccessSampleRate: 0.000001}
+
+		assert.Empty(t, serve(conf, respond(http.StatusOK, ""), "/"))
+		assert.Len(t, serve(conf, respond(http.StatusBadRequest, ""), "/"), 1)
+	})
+
+	t.Run("should log the request id set on the response", func(t *testing.T) {
+		entries := serve(AccessLogConfig{}, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set(requestutil.HeaderRequestID, "abc")
+		}, "/")
+
+		require.Len(t, entries, 1)
+		assert.Equal(t, "abc", entries[0].fields[logger.FieldRequestID])
+		assert.Equal(t, http.StatusOK, entries[0].fields["status"])
+	})
+}
@@ -0,0 +1,63 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/callicoder/go-commons/errors"
+	"github.com/callicoder/go-commons/errors/codes"
+	"github.com/callicoder/go-commons/handler/response"
+)
+
+const DefaultMaxBodyBytes = 10 << 20
+
+type BodyLimitConfig struct {
+	// MaxBytes is the default maximum size of request bodies, defaults to DefaultMaxBodyBytes
+	MaxBytes int64 `mapstructure:"max_bytes"`
+	// Routes overrides MaxBytes for the paths under the given prefixes, matched on whole
+	// path segments, the longest prefix wins. A negative value disables the limit.
+	Routes map[string]int64
+}
+
+// BodyLimit rejects requests with bodies larger than the configured limit with a
+// 413 status. Bodies without a content length are cut at the limit while being read.
+func BodyLimit(conf BodyLimitConfig) Middleware {
+	if conf.MaxBytes == 0 {
+		conf.MaxBytes = DefaultMaxBodyBytes
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			limit := conf.limit(r.URL.Path)
+			if limit < 0 || r.Body == nil || r.Body == http.NoBody {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			if r.ContentLength > limit {
+				response.BaseError(w, errors.WithCode(codes.PayloadTooLarge).Newf("Request body exceeds %d bytes", limit))
+				return
+			}
+
+			r.Body = http.MaxBytesReader(w, r.Body, limit)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func (c BodyLimitConfig) limit(path string) int64 {
+	limit, longest := c.MaxBytes, -1
+	for prefix, routeLimit := range c.Routes {
+		if hasPathPrefix(path, prefix) && len(prefix) > longest {
+			limit, longest = routeLimit, len(prefix)
+		}
+	}
+	return limit
+}
+
+// hasPathPrefix reports whether path is prefix or below it, so that /uploads matches
+// /uploads and /uploads/images but not /uploadsX.
+func hasPathPrefix(path, prefix string) bool {
+	prefix = strings.TrimSuffix(prefix, "/")
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
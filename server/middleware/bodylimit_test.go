@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBodyLimit(t *testing.T) {
+	h := BodyLimit(BodyLimitConfig{
+		MaxBytes: 8,
+		Routes:   map[string]int64{"/uploads": 64, "/uploads/raw": -1},
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := ioutil.ReadAll(r.Body); err != nil {
+			w.WriteHeader(http.StatusRequestEntityTooLarge)
+		}
+	}))
+
+	tests := []struct {
+		name     string
+		path     string
+		body     string
+		chunked  bool
+		expected int
+	}{
+		{"should accept small bodies", "/users", "small", false, http.StatusOK},
+		{"should reject large bodies", "/users", strings.Repeat("a", 9), false, http.StatusRequestEntityTooLarge},
+		{"should cut large bodies without content length", "/users", strings.Repeat("a", 9), true, http.StatusRequestEntityTooLarge},
+		{"should apply route overrides", "/uploads/images", strings.Repeat("a", 64), false, http.StatusOK},
+		{"should apply the longest route override", "/uploads/raw", strings.Repeat("a", 1024), false, http.StatusOK},
+		{"should apply route overrides to the exact path", "/uploads", strings.Repeat("a", 64), false, http.StatusOK},
+		{"should match route overrides on path segments", "/uploadsX", strings.Repeat("a", 9), false, http.StatusRequestEntityTooLarge},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, test.path, strings.NewReader(test.body))
+			if test.chunked {
+				req.ContentLength = -1
+			}
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			assert.Equal(t, test.expected, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package middleware
+
+import (
+	"fmt"
+
+	"github.com/callicoder/go-commons/statsd"
+)
+
+// Names of the built-in middlewares that can be enabled through Config.
+const (
+	NameCORS      = "cors"
+	NameRecover   = "recover"
+	NameRequestID = "request_id"
+	NameAccessLog = "access_log"
+	NameMetrics   = "metrics"
+	NameBodyLimit = "body_limit"
+)
+
+// DefaultEnabled is used when Config.Enabled is empty.
+var DefaultEnabled = []string{NameCORS, NameBodyLimit}
+
+type Config struct {
+	// Enabled lists the built-in middlewares to apply, outermost first.
+	Enabled   []string
+	CORS      CORSConfig
+	AccessLog AccessLogConfig `mapstructure:"access_log"`
+	Metrics   MetricsConfig
+	BodyLimit BodyLimitConfig `mapstructure:"body_limit"`
+}
+
+// Dependencies holds the runtime collaborators required by some built-in middlewares.
+// All of them are optional.
+type Dependencies struct {
+	Stats statsd.Client
+}
+
+// Factory creates a built-in middleware from the configuration.
+type Factory func(conf Config, deps Dependencies) Middleware
+
+var builtins = map[string]Factory{
+	NameCORS: func(conf Config, deps Dependencies) Middleware {
+		return CORS(conf.CORS)
+	},
+	NameRecover: func(conf Config, deps Dependencies) Middleware {
+		return Recover(deps.Stats)
+	},
+	NameRequestID: func(conf Config, deps Dependencies) Middleware {
+		return RequestID()
+	},
+	NameAccessLog: func(conf Config, deps Dependencies) Middleware {
+		return AccessLog(conf.AccessLog)
+	},
+	NameMetrics: func(conf Config, deps Dependencies) Middleware {
+		return Metrics(conf.Metrics, deps.Stats)
+	},
+	NameBodyLimit: func(conf Config, deps Dependencies) Middleware {
+		return BodyLimit(conf.BodyLimit)
+	},
+}
+
+// Build assembles the chain of built-in middlewares listed in conf.Enabled, in order.
+func Build(conf Config, deps Dependencies) (Chain, error) {
+	names := conf.Enabled
+	if len(names) == 0 {
+		names = DefaultEnabled
+	}
+
+	seen := make(map[string]bool, len(names))
+	middlewares := make([]Middleware, 0, len(names))
+	for _, name := range names {
+		factory, ok := builtins[name]
+		if !ok {
+			return Chain{}, fmt.Errorf("unknown middleware %q", name)
+		}
+		if seen[name] {
+			return Chain{}, fmt.Errorf("middleware %q enabled more than once", name)
+		}
+		seen[name] = true
+		middlewares = append(middlewares, factory(conf, deps))
+	}
+
+	return NewChain(middlewares...), nil
+}
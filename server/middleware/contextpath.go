@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+type contextKey int
+
+const contextPathKey contextKey = iota
+
+// ContextPath serves the next handler under contextPath, e.g. /api, stripping it from
+// the request path. Requests to the context path itself are redirected to it with a
+// trailing slash, and requests outside of it are answered with a 404.
+func ContextPath(contextPath string) Middleware {
+	strip := StripContextPath(contextPath)
+	route := RouteContextPath(contextPath, OutsideContextPath(contextPath))
+	return func(next http.Handler) http.Handler {
+		return strip(route(next))
+	}
+}
+
+// StripContextPath strips contextPath from the path of the requests under it, so that the
+// next handlers see paths relative to it, and marks them as such for RouteContextPath.
+// Other requests are passed on as is.
+func StripContextPath(contextPath string) Middleware {
+	prefix := contextPathPrefix(contextPath)
+	if prefix == "/" {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, prefix+"/") {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			r2 := r.WithContext(context.WithValue(r.Context(), contextPathKey, true))
+			r2.URL = new(url.URL)
+			*r2.URL = *r.URL
+			r2.URL.Path = strings.TrimPrefix(r.URL.Path, prefix)
+			if r.URL.RawPath != "" {
+				r2.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, prefix)
+			}
+			next.ServeHTTP(w, r2)
+		})
+	}
+}
+
+// RouteContextPath serves the requests stripped by StripContextPath with the next handler,
+// and the others with outside, e.g. OutsideContextPath.
+func RouteContextPath(contextPath string, outside http.Handler) Middleware {
+	if contextPathPrefix(contextPath) == "/" {
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if stripped, _ := r.Context().Value(contextPathKey).(bool); stripped {
+				next.ServeHTTP(w, r)
+				return
+			}
+			outside.ServeHTTP(w, r)
+		})
+	}
+}
+
+// OutsideContextPath redirects requests to contextPath itself to it with a trailing slash,
+// and answers the other ones with a 404.
+func OutsideContextPath(contextPath string) http.Handler {
+	prefix := contextPathPrefix(contextPath)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == prefix {
+			target := prefix + "/"
+			if r.URL.RawQuery != "" {
+				target += "?" + r.URL.RawQuery
+			}
+			http.Redirect(w, r, target, http.StatusMovedPermanently)
+			return
+		}
+		http.NotFound(w, r)
+	})
+}
+
+func contextPathPrefix(contextPath string) string {
+	return "/" + strings.Trim(contextPath, "/")
+}
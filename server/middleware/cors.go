@@ -0,0 +1,20 @@
+package middleware
+
+import (
+	"github.com/gorilla/handlers"
+)
+
+type CORSConfig struct {
+	AllowedOrigins []string `mapstructure:"allowed_origins"`
+	AllowedHeaders []string `mapstructure:"allowed_headers"`
+	AllowedMethods []string `mapstructure:"allowed_methods"`
+	MaxAge         int      `mapstructure:"maxage"`
+}
+
+func CORS(conf CORSConfig) Middleware {
+	allowedMethods := handlers.AllowedMethods(conf.AllowedMethods)
+	allowedHeaders := handlers.AllowedHeaders(conf.AllowedHeaders)
+	allowedOrigins := handlers.AllowedOrigins(conf.AllowedOrigins)
+	maxAge := handlers.MaxAge(conf.MaxAge)
+	return Middleware(handlers.CORS(allowedMethods, allowedHeaders, allowedOrigins, maxAge))
+}
@@ -0,0 +1,65 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/callicoder/go-commons/logger"
+	"github.com/callicoder/go-commons/statsd"
+)
+
+const defaultMetricsPrefix = "http.server"
+
+type MetricsConfig struct {
+	// Prefix of the metric names, defaults to http.server
+	Prefix string
+	// Routes lists route templates such as /users/:id used to tag requests. Paths not
+	// matching any of them are tagged route:unmatched. Without routes, paths have their
+	// identifier-like segments replaced by :id, and not found responses are tagged
+	// route:unmatched.
+	Routes []string
+}
+
+// Metrics reports the count, latency and status class of every request to stats,
+// tagged with the method, route template and status.
+func Metrics(conf MetricsConfig, stats statsd.Client) Middleware {
+	prefix := conf.Prefix
+	if prefix == "" {
+		prefix = defaultMetricsPrefix
+	}
+	templater := NewRouteTemplater(conf.Routes)
+
+	return func(next http.Handler) http.Handler {
+		if stats == nil {
+			return next
+		}
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+			rw := WrapResponseWriter(w)
+			next.ServeHTTP(rw, r)
+			latency := time.Since(start)
+
+			status := rw.Status()
+			statusClass := fmt.Sprintf("%dxx", status/100)
+			tags := []string{
+				"method:" + r.Method,
+				"route:" + templater.TemplateResponse(r.URL.Path, status),
+				"status:" + strconv.Itoa(status),
+				"status_class:" + statusClass,
+			}
+
+			if err := stats.IncrementWithTags(prefix+".requests", tags...); err != nil {
+				logger.Errorf("Failed to report request metric: %v", err)
+			}
+			if err := stats.IncrementWithTags(prefix+".responses."+statusClass, tags[:2]...); err != nil {
+				logger.Errorf("Failed to report response metric: %v", err)
+			}
+			if err := stats.TimingWithTags(prefix+".latency", latency, tags...); err != nil {
+				logger.Errorf("Failed to report latency metric: %v", err)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/callicoder/go-commons/statsd/statsdtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMetrics(t *testing.T) {
+	respond := func(status int) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		})
+	}
+
+	t.Run("should report count, status class and latency per route", func(t *testing.T) {
+		stats := statsdtest.NewClient()
+		h := Metrics(MetricsConfig{}, stats)(respond(http.StatusBadRequest))
+
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/1234", nil))
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/users/5678", nil))
+
+		stats.AssertCounter(t, "http.server.requests", 2)
+		stats.AssertTagged(t, "http.server.requests", "method:GET", "route:/users/:id", "status:400", "status_class:4xx")
+		stats.AssertCounter(t, "http.server.responses.4xx", 2)
+		stats.AssertTagged(t, "http.server.responses.4xx", "method:GET", "route:/users/:id")
+		latencies := stats.Find("http.server.latency")
+		require.Len(t, latencies, 2)
+		assert.Equal(t, statsdtest.Timing, latencies[0].Type)
+		assert.Contains(t, latencies[0].Tags, "status_class:4xx")
+	})
+
+	t.Run("should use the configured prefix and routes", func(t *testing.T) {
+		stats := statsdtest.NewClient()
+		conf := MetricsConfig{Prefix: "api", Routes: []string{"/users/:name"}}
+		h := Metrics(conf, stats)(respond(http.StatusOK))
+
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/users/sachin", nil))
+
+		stats.AssertCounter(t, "api.requests", 1)
+		stats.AssertTagged(t, "api.requests", "method:POST", "route:/users/:name", "status:200", "status_class:2xx")
+		stats.AssertCounter(t, "api.responses.2xx", 1)
+		assert.Empty(t, stats.Find("http.server.requests"))
+	})
+
+	t.Run("should bound the route tags of unmatched paths", func(t *testing.T) {
+		routeTags := func(conf MetricsConfig, status int) map[string]bool {
+			stats := statsdtest.NewClient()
+			h := Metrics(conf, stats)(respond(status))
+			for i := 0; i < 100; i++ {
+				path := fmt.Sprintf("/%s/%s", strconv.FormatInt(rand.Int63(), 36), strconv.FormatInt(rand.Int63(), 36))
+				h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+			}
+
+			routes := map[string]bool{}
+			for _, metric := range stats.Find("http.server.requests") {
+				for _, tag := range metric.Tags {
+					if strings.HasPrefix(tag, "route:") {
+						routes[tag] = true
+					}
+				}
+			}
+			return routes
+		}
+		unmatched := map[string]bool{"route:" + RouteUnmatched: true}
+
+		assert.Equal(t, unmatched, routeTags(MetricsConfig{Routes: []string{"/users/:name"}}, http.StatusOK))
+		assert.Equal(t, unmatched, routeTags(MetricsConfig{Routes: []string{"/users/:name"}}, http.StatusNotFound))
+		assert.Equal(t, unmatched, routeTags(MetricsConfig{}, http.StatusNotFound))
+	})
+
+	t.Run("should pass through without a client", func(t *testing.T) {
+		next := respond(http.StatusOK)
+		rec := httptest.NewRecorder()
+
+		Metrics(MetricsConfig{}, nil)(next).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusOK, rec.Code)
+	})
+}
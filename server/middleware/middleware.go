@@ -0,0 +1,58 @@
+package middleware
+
+import (
+	"net/http"
+)
+
+// Middleware wraps an http.Handler with additional behaviour.
+type Middleware func(http.Handler) http.Handler
+
+// Chain is an immutable, ordered list of middlewares.
+// The first middleware in the chain is the outermost one, i.e. it sees the
+// request first and the response last.
+type Chain struct {
+	middlewares []Middleware
+}
+
+func NewChain(middlewares ...Middleware) Chain {
+	return Chain{}.Append(middlewares...)
+}
+
+// Append returns a new chain with the given middlewares added after the existing ones.
+func (c Chain) Append(middlewares ...Middleware) Chain {
+	mws := make([]Middleware, 0, len(c.middlewares)+len(middlewares))
+	mws = append(mws, c.middlewares...)
+	for _, mw := range middlewares {
+		if mw != nil {
+			mws = append(mws, mw)
+		}
+	}
+	return Chain{middlewares: mws}
+}
+
+// Extend returns a new chain with the middlewares of other added after the existing ones.
+func (c Chain) Extend(other Chain) Chain {
+	return c.Append(other.middlewares...)
+}
+
+// Then wraps h with all the middlewares of the chain.
+func (c Chain) Then(h http.Handler) http.Handler {
+	if h == nil {
+		h = http.DefaultServeMux
+	}
+	for i := len(c.middlewares) - 1; i >= 0; i-- {
+		h = c.middlewares[i](h)
+	}
+	return h
+}
+
+func (c Chain) ThenFunc(fn http.HandlerFunc) http.Handler {
+	if fn == nil {
+		return c.Then(nil)
+	}
+	return c.Then(fn)
+}
+
+func (c Chain) Len() int {
+	return len(c.middlewares)
+}
@@ -0,0 +1,61 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func tag(name string, calls *[]string) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			*calls = append(*calls, name)
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func TestChain(t *testing.T) {
+	t.Run("should run middlewares in the order they were added", func(t *testing.T) {
+		var calls []string
+		chain := NewChain(tag("first", &calls), tag("second", &calls)).Append(tag("third", &calls))
+
+		h := chain.ThenFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls = append(calls, "handler")
+		})
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, []string{"first", "second", "third", "handler"}, calls)
+	})
+
+	t.Run("should not modify the original chain on append", func(t *testing.T) {
+		var calls []string
+		base := NewChain(tag("base", &calls))
+		_ = base.Append(tag("extra", &calls))
+
+		assert.Equal(t, 1, base.Len())
+	})
+}
+
+func TestBuild(t *testing.T) {
+	t.Run("should enable the default middlewares", func(t *testing.T) {
+		chain, err := Build(Config{}, Dependencies{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, len(DefaultEnabled), chain.Len())
+	})
+
+	t.Run("should fail on unknown middleware", func(t *testing.T) {
+		_, err := Build(Config{Enabled: []string{"unknown"}}, Dependencies{})
+
+		assert.Error(t, err)
+	})
+
+	t.Run("should fail on duplicate middleware", func(t *testing.T) {
+		_, err := Build(Config{Enabled: []string{NameCORS, NameCORS}}, Dependencies{})
+
+		assert.Error(t, err)
+	})
+}
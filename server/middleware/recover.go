@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"runtime/debug"
+
+	"github.com/callicoder/go-commons/errors"
+	"github.com/callicoder/go-commons/handler/response"
+	"github.com/callicoder/go-commons/logger"
+	"github.com/callicoder/go-commons/requestutil"
+	"github.com/callicoder/go-commons/statsd"
+)
+
+const metricPanics = "http.server.panics"
+
+// Recover catches panics raised by the next handlers, logs them with their stack trace
+// and responds with an internal BaseError. The stats client is optional.
+func Recover(stats statsd.Client) Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			rw := WrapResponseWriter(w)
+
+			defer func() {
+				rec := recover()
+				if rec == nil {
+					return
+				}
+				if rec == http.ErrAbortHandler {
+					// Let net/http abort the response silently
+					panic(rec)
+				}
+
+				logger.FromContext(r.Context()).WithFields(logger.Fields{
+					"method":     r.Method,
+					"path":       r.URL.Path,
+					"client_ip":  requestutil.GetIpAddress(r).String(),
+					"user_agent": r.UserAgent(),
+					"stack":      string(debug.Stack()),
+				}).Errorf("Recovered from panic: %v", rec)
+
+				if stats != nil {
+					if err := stats.IncrementWithTags(metricPanics, "method:"+r.Method); err != nil {
+						logger.Errorf("Failed to report panic metric: %v", err)
+					}
+				}
+
+				if rw.Written() {
+					// Headers are already sent, nothing more can be reported to the client
+					return
+				}
+				response.BaseError(rw, errors.New(http.StatusText(http.StatusInternalServerError)))
+			}()
+
+			next.ServeHTTP(rw, r)
+		})
+	}
+}
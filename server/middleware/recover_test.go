@@ -0,0 +1,42 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/callicoder/go-commons/logger"
+	"github.com/callicoder/go-commons/statsd/statsdtest"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRecover(t *testing.T) {
+	logger.SetupRootLogger(logger.Config{Level: "panic"})
+
+	t.Run("should respond with an internal error on panic", func(t *testing.T) {
+		stats := statsdtest.NewClient()
+		h := Recover(stats)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			panic("boom")
+		}))
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusInternalServerError, rec.Code)
+		assert.JSONEq(t, `{"code":"internal","message":"Internal Server Error"}`, rec.Body.String())
+		stats.AssertCounter(t, "http.server.panics", 1)
+	})
+
+	t.Run("should not override a response already sent", func(t *testing.T) {
+		h := Recover(nil)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusAccepted)
+			panic("boom")
+		}))
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		assert.Equal(t, http.StatusAccepted, rec.Code)
+		assert.Empty(t, rec.Body.String())
+	})
+}
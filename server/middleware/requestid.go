@@ -0,0 +1,24 @@
+package middleware
+
+import (
+	"net/http"
+
+	"github.com/callicoder/go-commons/requestutil"
+)
+
+// RequestID reads the X-Request-ID header of the request, or generates a new id when it is
+// missing or invalid, stores it in the request context and echoes it in the response.
+func RequestID() Middleware {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requestID := requestutil.GetRequestID(r)
+			if requestID == "" {
+				requestID = requestutil.NewRequestID()
+			}
+
+			w.Header().Set(requestutil.HeaderRequestID, requestID)
+			ctx := requestutil.WithRequestID(r.Context(), requestID)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
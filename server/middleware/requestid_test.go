@@ -0,0 +1,37 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/callicoder/go-commons/requestutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestID(t *testing.T) {
+	var requestID string
+	h := RequestID()(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestID = requestutil.RequestID(r.Context())
+	}))
+
+	t.Run("should propagate the request id sent by the client", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(requestutil.HeaderRequestID, "abc-123")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		assert.Equal(t, "abc-123", requestID)
+		assert.Equal(t, "abc-123", rec.Header().Get(requestutil.HeaderRequestID))
+	})
+
+	t.Run("should generate a request id when missing or invalid", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set(requestutil.HeaderRequestID, "not valid")
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		assert.Len(t, requestID, 32)
+		assert.Equal(t, requestID, rec.Header().Get(requestutil.HeaderRequestID))
+	})
+}
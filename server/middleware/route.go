@@ -0,0 +1,119 @@
+package middleware
+
+import (
+	"net/http"
+	"strings"
+)
+
+const routeParam = ":id"
+
+// RouteUnmatched is the route of the paths which match none of the configured routes,
+// and of the not found responses which match none of them.
+const RouteUnmatched = "unmatched"
+
+// RouteTemplater maps request paths to low cardinality route templates, so that
+// ids in paths do not end up as distinct metric tags.
+type RouteTemplater struct {
+	routes [][]string
+}
+
+// NewRouteTemplater creates a templater matching paths against the given route
+// templates, e.g. /users/:id/orders or /static/*. Paths which do not match any of them
+// are reported as RouteUnmatched. Without templates, segments of paths which look like
+// identifiers are replaced by :id.
+func NewRouteTemplater(routes []string) *RouteTemplater {
+	t := &RouteTemplater{}
+	for _, route := range routes {
+		t.routes = append(t.routes, splitPath(route))
+	}
+	return t
+}
+
+func (t *RouteTemplater) Template(path string) string {
+	if route, ok := t.Match(path); ok {
+		return route
+	}
+	if len(t.routes) > 0 {
+		return RouteUnmatched
+	}
+
+	segments := splitPath(path)
+	for i, segment := range segments {
+		if isIdentifier(segment) {
+			segments[i] = routeParam
+		}
+	}
+	return "/" + strings.Join(segments, "/")
+}
+
+// TemplateResponse is Template for a response with the given status. Not found responses
+// are never templated from their path, which may be anything, and are reported as
+// RouteUnmatched unless they match a configured route.
+func (t *RouteTemplater) TemplateResponse(path string, status int) string {
+	if status != http.StatusNotFound {
+		return t.Template(path)
+	}
+	if route, ok := t.Match(path); ok {
+		return route
+	}
+	return RouteUnmatched
+}
+
+// Match returns the configured route template matching path, if any.
+func (t *RouteTemplater) Match(path string) (string, bool) {
+	segments := splitPath(path)
+	for _, route := range t.routes {
+		if matchRoute(route, segments) {
+			return "/" + strings.Join(route, "/"), true
+		}
+	}
+	return "", false
+}
+
+func matchRoute(route, segments []string) bool {
+	for i, part := range route {
+		if part == "*" {
+			return true
+		}
+		if i >= len(segments) {
+			return false
+		}
+		if !strings.HasPrefix(part, ":") && part != segments[i] {
+			return false
+		}
+	}
+	return len(route) == len(segments)
+}
+
+func splitPath(path string) []string {
+	path = strings.Trim(path, "/")
+	if path == "" {
+		return []string{}
+	}
+	return strings.Split(path, "/")
+}
+
+// isIdentifier reports whether a path segment looks like a numeric id, a uuid or a hash
+func isIdentifier(segment string) bool {
+	if segment == "" {
+		return false
+	}
+
+	digits, hex := 0, 0
+	for _, c := range segment {
+		switch {
+		case c >= '0' && c <= '9':
+			digits++
+		case (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F'):
+			hex++
+		case c == '-':
+		default:
+			return false
+		}
+	}
+
+	if digits == len(segment) {
+		return true
+	}
+	return digits > 0 && digits+hex+strings.Count(segment, "-") == len(segment) && digits+hex >= 16
+}
@@ -0,0 +1,57 @@
+package middleware
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRouteTemplater(t *testing.T) {
+	t.Run("should match configured routes", func(t *testing.T) {
+		templater := NewRouteTemplater([]string{"/users/:name/orders", "/static/*", "/"})
+
+		tests := []struct {
+			path     string
+			expected string
+		}{
+			{"/", "/"},
+			{"/users/sachin/orders", "/users/:name/orders"},
+			{"/static/js/app.js", "/static/*"},
+			{"/users/sachin", RouteUnmatched},
+			{"/orders/1234", RouteUnmatched},
+		}
+
+		for _, test := range tests {
+			assert.Equal(t, test.expected, templater.Template(test.path), test.path)
+		}
+	})
+
+	t.Run("should replace identifiers without configured routes", func(t *testing.T) {
+		templater := NewRouteTemplater(nil)
+
+		tests := []struct {
+			path     string
+			expected string
+		}{
+			{"/", "/"},
+			{"/ping", "/ping"},
+			{"/orders/1234", "/orders/:id"},
+			{"/orders/3f2504e0-4f89-11d3-9a0c-0305e82c3301/items/7", "/orders/:id/items/:id"},
+			{"/v1/api/2fa", "/v1/api/2fa"},
+		}
+
+		for _, test := range tests {
+			assert.Equal(t, test.expected, templater.Template(test.path), test.path)
+		}
+	})
+
+	t.Run("should not template not found responses", func(t *testing.T) {
+		templater := NewRouteTemplater([]string{"/users/:name"})
+
+		assert.Equal(t, "/users/:name", templater.TemplateResponse("/users/sachin", http.StatusNotFound))
+		assert.Equal(t, RouteUnmatched, templater.TemplateResponse("/wp-admin/setup.php", http.StatusNotFound))
+		assert.Equal(t, RouteUnmatched, NewRouteTemplater(nil).TemplateResponse("/orders/1234", http.StatusNotFound))
+		assert.Equal(t, "/orders/:id", NewRouteTemplater(nil).TemplateResponse("/orders/1234", http.StatusOK))
+	})
+}
@@ -2,58 +2,371 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"log"
 	"net"
 	"net/http"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/gorilla/handlers"
+	"github.com/callicoder/go-commons/logger"
+	"github.com/callicoder/go-commons/server/middleware"
+	"github.com/callicoder/go-commons/statsd"
+)
+
+var (
+	ErrServerStarted    = errors.New("server already started")
+	ErrServerNotStarted = errors.New("server not started")
 )
 
 type Server struct {
 	server *http.Server
 	config Config
+	tls    *certReloader
+	admin  *http.Server
+	errs   chan error
+	// done is closed once the main server stops serving requests
+	done chan struct{}
+	// failed is closed once the main or the admin server fails, after serveErr is set
+	failed       chan struct{}
+	serveErr     error
+	shuttingDown chan struct{}
+	shutdownOnce sync.Once
+	// listen opens the listeners, it is replaced in tests
+	listen func(network, address string) (net.Listener, error)
+
+	mu      sync.Mutex
+	started bool
+}
+
+type Option func(*options)
+
+type options struct {
+	middlewares []middleware.Middleware
+	stats       statsd.Client
+	handlers    map[string]http.Handler
+
+	liveness      http.Handler
+	readiness     http.Handler
+	metrics       http.Handler
+	buildInfo     BuildInfo
+	adminHandlers map[string]http.Handler
+}
+
+// WithMiddleware appends custom middlewares after the built-in ones enabled in Config.
+func WithMiddleware(middlewares ...middleware.Middleware) Option {
+	return func(o *options) {
+		o.middlewares = append(o.middlewares, middlewares...)
+	}
+}
+
+// WithStatsd sets the client used by the built-in middlewares to report metrics.
+func WithStatsd(client statsd.Client) Option {
+	return func(o *options) {
+		o.stats = client
+	}
+}
+
+// WithHandler mounts h on pattern next to the main handler of the server, e.g. the
+// handler returned by statsd.Reporter.Handler on /metrics. Patterns are matched outside
+// of Config.ContextPath. A nil h is ignored, as returned by Reporter.Handler when metrics
+// are pushed.
+func WithHandler(pattern string, h http.Handler) Option {
+	return func(o *options) {
+		if h == nil {
+			return
+		}
+		if o.handlers == nil {
+			o.handlers = make(map[string]http.Handler)
+		}
+		o.handlers[pattern] = h
+	}
 }
 
-func New(conf Config, handler http.Handler) *Server {
-	allowedMethods := handlers.AllowedMethods(conf.CORS.AllowedMethods)
-	allowedHeaders := handlers.AllowedHeaders(conf.CORS.AllowedHeaders)
-	allowedOrigins := handlers.AllowedOrigins(conf.CORS.AllowedOrigins)
-	maxAge := handlers.MaxAge(conf.CORS.MaxAge)
-	handler = handlers.CORS(allowedMethods, allowedHeaders, allowedOrigins, maxAge)(handler)
+// WithLivenessHandler replaces the default /health/live handler of the admin listener.
+func WithLivenessHandler(h http.Handler) Option {
+	return func(o *options) {
+		o.liveness = h
+	}
+}
+
+// WithReadinessHandler replaces the default /health/ready handler of the admin listener.
+// Readiness always fails once the server is shutting down.
+func WithReadinessHandler(h http.Handler) Option {
+	return func(o *options) {
+		o.readiness = h
+	}
+}
 
-	return &Server{
+// WithMetricsHandler sets the /metrics handler of the admin listener. It defaults to the
+// handler of the statsd client set with WithStatsd, when its backend is scraped.
+func WithMetricsHandler(h http.Handler) Option {
+	return func(o *options) {
+		o.metrics = h
+	}
+}
+
+func WithBuildInfo(info BuildInfo) Option {
+	return func(o *options) {
+		o.buildInfo = info
+	}
+}
+
+// WithAdminHandler mounts h on pattern on the admin listener. A nil h is ignored.
+func WithAdminHandler(pattern string, h http.Handler) Option {
+	return func(o *options) {
+		if h == nil {
+			return
+		}
+		if o.adminHandlers == nil {
+			o.adminHandlers = make(map[string]http.Handler)
+		}
+		o.adminHandlers[pattern] = h
+	}
+}
+
+func New(conf Config, handler http.Handler, opts ...Option) (*Server, error) {
+	o := &options{}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if err := conf.TLS.validate(); err != nil {
+		return nil, err
+	}
+
+	chain, err := middleware.Build(conf.middlewareConfig(), middleware.Dependencies{Stats: o.stats})
+	if err != nil {
+		return nil, fmt.Errorf("%w :: Failed to build middleware chain", err)
+	}
+	if handler == nil {
+		handler = http.DefaultServeMux
+	}
+	var mux *http.ServeMux
+	if len(o.handlers) > 0 {
+		mux = http.NewServeMux()
+		for pattern, h := range o.handlers {
+			mux.Handle(pattern, h)
+		}
+	}
+	if strings.Trim(conf.ContextPath, "/") != "" {
+		// Requests outside of the context path go to the handlers set with WithHandler,
+		// or are redirected or answered with a 404, within the middlewares
+		outside := middleware.OutsideContextPath(conf.ContextPath)
+		if mux != nil {
+			mux.Handle("/", outside)
+			outside = mux
+		}
+		handler = middleware.RouteContextPath(conf.ContextPath, outside)(handler)
+	} else if mux != nil {
+		mux.Handle("/", handler)
+		handler = mux
+	}
+	handler = chain.Append(o.middlewares...).Then(handler)
+	// Middlewares see paths relative to the context path
+	handler = middleware.StripContextPath(conf.ContextPath)(handler)
+
+	s := &Server{
 		server: &http.Server{
-			Handler:      handler,
-			ReadTimeout:  time.Duration(conf.ReadTimeoutMs) * time.Millisecond,
-			WriteTimeout: time.Duration(conf.WriteTimeoutMs) * time.Millisecond,
-			Addr:         fmt.Sprintf("0.0.0.0:%d", conf.Port),
+			Handler:           handler,
+			ReadTimeout:       time.Duration(conf.ReadTimeoutMs) * time.Millisecond,
+			ReadHeaderTimeout: time.Duration(conf.ReadHeaderTimeoutMs) * time.Millisecond,
+			WriteTimeout:      time.Duration(conf.WriteTimeoutMs) * time.Millisecond,
+			IdleTimeout:       time.Duration(conf.IdleTimeoutMs) * time.Millisecond,
+			MaxHeaderBytes:    conf.MaxHeaderBytes,
+			Addr:              conf.addr(),
 		},
 		config: conf,
+		errs:   make(chan error, 2),
+		done:   make(chan struct{}),
+		failed: make(chan struct{}),
+		listen: net.Listen,
+
+		shuttingDown: make(chan struct{}),
 	}
+
+	if conf.Admin.Enabled() {
+		s.admin = s.newAdminServer(o)
+	}
+
+	if conf.TLS.Enabled() {
+		reloader, err := newCertReloader(conf.TLS)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig, err := newTLSConfig(conf.TLS, reloader)
+		if err != nil {
+			return nil, err
+		}
+		s.tls = reloader
+		s.server.TLSConfig = tlsConfig
+		if conf.TLS.ClientCAFile != "" {
+			s.server.Handler = peerIdentity(s.server.Handler)
+		}
+	}
+
+	return s, nil
 }
 
+// Start starts serving requests in the background. A server can only be started once,
+// and not after it has been shut down.
 func (s *Server) Start() error {
-	lis, err := net.Listen("tcp", s.server.Addr)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return ErrServerStarted
+	}
+	select {
+	case <-s.shuttingDown:
+		return http.ErrServerClosed
+	default:
+	}
+
+	lis, err := s.listen("tcp", s.server.Addr)
 	if err != nil {
 		return fmt.Errorf("%w :: Failed to start listener on %s", err, s.server.Addr)
 	}
 
+	if s.admin != nil {
+		adminLis, err := s.listen("tcp", s.admin.Addr)
+		if err != nil {
+			lis.Close()
+			return fmt.Errorf("%w :: Failed to start admin listener on %s", err, s.admin.Addr)
+		}
+
+		go func() {
+			serverLogger().Infof("Starting admin http server on %v", s.admin.Addr)
+			err := s.admin.Serve(adminLis)
+			if err != nil && err != http.ErrServerClosed {
+				serverLogger().Errorf("Failed to serve admin http requests %v", err)
+				s.fail(err)
+			}
+		}()
+	}
+
+	if s.tls != nil && s.config.TLS.ReloadIntervalMs > 0 {
+		s.tls.start(time.Duration(s.config.TLS.ReloadIntervalMs) * time.Millisecond)
+	}
+
 	go func() {
-		log.Printf("Starting http server on port %v", s.config.Port)
-		err := s.server.Serve(lis)
+		defer close(s.done)
+		if s.tls != nil {
+			defer s.tls.close()
+		}
+		var err error
+		if s.tls != nil {
+			serverLogger().Infof("Starting https server on %v", s.server.Addr)
+			err = s.server.ServeTLS(lis, "", "")
+		} else {
+			serverLogger().Infof("Starting http server on %v", s.server.Addr)
+			err = s.server.Serve(lis)
+		}
 		if err != nil && err != http.ErrServerClosed {
-			log.Fatalf("Failed to start http server %v", err)
+			serverLogger().Errorf("Failed to serve http requests %v", err)
+			s.fail(err)
 		}
 	}()
 
+	s.started = true
 	return nil
 }
 
+// serverLogger returns the root logger, or the standard logger when the root logger is not
+// set up, as servers do not require it.
+func serverLogger() logger.Logger {
+	return logger.FromContextOrStd(context.Background())
+}
+
+// fail reports err, which stopped the main or the admin server from serving requests.
+func (s *Server) fail(err error) {
+	s.errs <- err
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.serveErr == nil {
+		s.serveErr = err
+		close(s.failed)
+	}
+}
+
+func (s *Server) serveError() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.serveErr
+}
+
+// Err returns a channel receiving the errors which stopped the server or the admin
+// server from serving requests after Start. It is not closed on Shutdown.
+func (s *Server) Err() <-chan error {
+	return s.errs
+}
+
+// Wait blocks until the server started with Start stops serving requests or its admin
+// server fails, and returns the first serve error, or nil if it was shut down. It
+// returns ErrServerNotStarted if the server was not started.
+func (s *Server) Wait() error {
+	s.mu.Lock()
+	started := s.started
+	s.mu.Unlock()
+	if !started {
+		return ErrServerNotStarted
+	}
+
+	select {
+	case <-s.done:
+	case <-s.failed:
+	}
+	return s.serveError()
+}
+
+// ListenAndServe starts the server and blocks until it or its admin server fails, or
+// ctx is done. The server is then gracefully shut down.
+func (s *Server) ListenAndServe(ctx context.Context) error {
+	if err := s.Start(); err != nil {
+		return err
+	}
+
+	select {
+	case <-ctx.Done():
+		err := s.Shutdown()
+		<-s.done
+		return err
+	case <-s.failed:
+		if err := s.Shutdown(); err != nil {
+			serverLogger().Errorf("Failed to shut down http server %v", err)
+		}
+		<-s.done
+		return s.serveError()
+	case <-s.done:
+		return s.serveError()
+	}
+}
+
 func (s *Server) Shutdown() error {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(s.config.GracefulShutdownTimeoutMs)*time.Millisecond)
 	defer cancel()
-	log.Printf("Shutting down http server")
-	return s.server.Shutdown(ctx)
+	return s.ShutdownContext(ctx)
+}
+
+// ShutdownContext gracefully shuts down the server, waiting for active requests until ctx is done.
+// The admin listener is shut down last, so that it reports the server as not ready meanwhile.
+func (s *Server) ShutdownContext(ctx context.Context) error {
+	s.shutdownOnce.Do(func() {
+		close(s.shuttingDown)
+	})
+
+	serverLogger().Infof("Shutting down http server")
+	if s.tls != nil {
+		s.tls.close()
+	}
+	err := s.server.Shutdown(ctx)
+
+	if s.admin != nil {
+		serverLogger().Infof("Shutting down admin http server")
+		if adminErr := s.admin.Shutdown(ctx); err == nil {
+			err = adminErr
+		}
+	}
+	return err
 }
@@ -0,0 +1,268 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"github.com/callicoder/go-commons/handler"
+	"github.com/callicoder/go-commons/logger"
+	"github.com/callicoder/go-commons/requestutil"
+	"github.com/callicoder/go-commons/server/middleware"
+	"github.com/callicoder/go-commons/statsd"
+	"github.com/callicoder/go-commons/statsd/statsdtest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// failingListener fails to accept connections once fail is closed
+type failingListener struct {
+	net.Listener
+	fail <-chan struct{}
+	err  error
+}
+
+func (l *failingListener) Accept() (net.Conn, error) {
+	<-l.fail
+	return nil, l.err
+}
+
+func TestServer(t *testing.T) {
+	logger.SetupRootLogger(logger.Config{Level: "panic"})
+
+	t.Run("should fail on unknown middlewares", func(t *testing.T) {
+		_, err := New(Config{Middlewares: []string{"unknown"}}, http.HandlerFunc(handler.PingHandler))
+
+		assert.Error(t, err)
+	})
+
+	t.Run("should ignore nil handlers", func(t *testing.T) {
+		reporter, err := statsd.NewClient(statsd.Config{Enabled: false})
+		assert.NoError(t, err)
+
+		s, err := New(Config{}, http.HandlerFunc(handler.PingHandler), WithHandler("/metrics", reporter.Handler()))
+		assert.NoError(t, err)
+
+		rec := httptest.NewRecorder()
+		s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+		assert.Equal(t, "pong", rec.Body.String())
+	})
+
+	t.Run("should stop serving when the context is done", func(t *testing.T) {
+		s, err := New(Config{GracefulShutdownTimeoutMs: 1000}, http.HandlerFunc(handler.PingHandler))
+		assert.NoError(t, err)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		assert.NoError(t, s.ListenAndServe(ctx))
+		assert.NoError(t, s.Wait())
+	})
+
+	t.Run("should only start once", func(t *testing.T) {
+		s, err := New(Config{GracefulShutdownTimeoutMs: 1000}, http.HandlerFunc(handler.PingHandler))
+		require.NoError(t, err)
+
+		require.NoError(t, s.Start())
+		assert.Equal(t, ErrServerStarted, s.Start())
+
+		assert.NoError(t, s.Shutdown())
+		assert.NoError(t, s.Wait())
+		assert.Equal(t, ErrServerStarted, s.Start())
+	})
+
+	t.Run("should not start after shutdown", func(t *testing.T) {
+		s, err := New(Config{GracefulShutdownTimeoutMs: 1000}, http.HandlerFunc(handler.PingHandler))
+		require.NoError(t, err)
+
+		assert.NoError(t, s.Shutdown())
+		assert.Equal(t, http.ErrServerClosed, s.Start())
+		assert.Equal(t, ErrServerNotStarted, s.Wait())
+	})
+
+	t.Run("should report serve errors after start", func(t *testing.T) {
+		s, err := New(Config{}, http.HandlerFunc(handler.PingHandler))
+		require.NoError(t, err)
+		errAccept := errors.New("accept failed")
+		fail := make(chan struct{})
+		s.listen = func(network, address string) (net.Listener, error) {
+			lis, err := net.Listen(network, address)
+			return &failingListener{Listener: lis, fail: fail, err: errAccept}, err
+		}
+
+		require.NoError(t, s.Start())
+		close(fail)
+
+		select {
+		case err := <-s.Err():
+			assert.Equal(t, errAccept, err)
+		case <-time.After(time.Second):
+			t.Fatal("serve error not reported")
+		}
+		assert.Equal(t, errAccept, s.Wait())
+	})
+
+	t.Run("should serve the context path within the middlewares", func(t *testing.T) {
+		stats := statsdtest.NewClient()
+		var path string
+		main := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			path = r.URL.Path
+		})
+		metrics := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			_, _ = w.Write([]byte("metrics"))
+		})
+		s, err := New(Config{
+			ContextPath: "/api",
+			Middlewares: []string{middleware.NameRecover, middleware.NameRequestID, middleware.NameMetrics},
+		}, main, WithStatsd(stats), WithHandler("/metrics", metrics))
+		require.NoError(t, err)
+		serve := func(target string) *httptest.ResponseRecorder {
+			rec := httptest.NewRecorder()
+			s.server.Handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+			return rec
+		}
+
+		rec := serve("/api/users")
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, "/users", path)
+		stats.AssertTagged(t, "http.server.requests", "route:/users", "status:200")
+
+		rec = serve("/api/metrics")
+		assert.Equal(t, "/metrics", path)
+		assert.Empty(t, rec.Body.String())
+
+		rec = serve("/metrics")
+		assert.Equal(t, "metrics", rec.Body.String())
+
+		rec = serve("/api")
+		assert.Equal(t, http.StatusMovedPermanently, rec.Code)
+		assert.NotEmpty(t, rec.Header().Get(requestutil.HeaderRequestID))
+
+		rec = serve("/other")
+		assert.Equal(t, http.StatusNotFound, rec.Code)
+		assert.NotEmpty(t, rec.Header().Get(requestutil.HeaderRequestID))
+		stats.AssertTagged(t, "http.server.requests", "route:"+middleware.RouteUnmatched, "status:404")
+	})
+
+	t.Run("should return listener errors", func(t *testing.T) {
+		s, err := New(Config{}, http.HandlerFunc(handler.PingHandler))
+		assert.NoError(t, err)
+		s.server.Addr = "invalid:address"
+
+		assert.Error(t, s.ListenAndServe(context.Background()))
+	})
+}
+
+func TestAdminServer(t *testing.T) {
+	logger.SetupRootLogger(logger.Config{Level: "panic"})
+
+	port := freePort(t)
+	s, err := New(Config{
+		Host:  "127.0.0.1",
+		Port:  freePort(t),
+		Admin: AdminConfig{Port: port, Pprof: true},
+	}, http.HandlerFunc(handler.PingHandler), WithBuildInfo(BuildInfo{Version: "1.2.3"}))
+	require.NoError(t, err)
+	require.NoError(t, s.Start())
+	defer s.Shutdown()
+
+	get := func(path string) (int, string) {
+		resp, err := http.Get(fmt.Sprintf("http://127.0.0.1:%d%s", port, path))
+		require.NoError(t, err)
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		require.NoError(t, err)
+		return resp.StatusCode, string(body)
+	}
+
+	status, _ := get("/health/live")
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _ = get("/health/ready")
+	assert.Equal(t, http.StatusOK, status)
+
+	status, body := get("/info")
+	assert.Equal(t, http.StatusOK, status)
+	assert.Contains(t, body, `"version":"1.2.3"`)
+
+	status, _ = get("/debug/pprof/")
+	assert.Equal(t, http.StatusOK, status)
+
+	status, _ = get("/metrics")
+	assert.Equal(t, http.StatusNotFound, status)
+}
+
+func TestAdminServerFailure(t *testing.T) {
+	logger.SetupRootLogger(logger.Config{Level: "panic"})
+
+	adminPort := freePort(t)
+	s, err := New(Config{
+		Host:                      "127.0.0.1",
+		Port:                      freePort(t),
+		GracefulShutdownTimeoutMs: 1000,
+		Admin:                     AdminConfig{Port: adminPort},
+	}, http.HandlerFunc(handler.PingHandler))
+	require.NoError(t, err)
+
+	errAccept := errors.New("accept failed")
+	fail := make(chan struct{})
+	s.listen = func(network, address string) (net.Listener, error) {
+		lis, err := net.Listen(network, address)
+		if err != nil || address != s.admin.Addr {
+			return lis, err
+		}
+		return &failingListener{Listener: lis, fail: fail, err: errAccept}, nil
+	}
+
+	served := make(chan error, 1)
+	go func() {
+		served <- s.ListenAndServe(context.Background())
+	}()
+	close(fail)
+
+	select {
+	case err := <-served:
+		assert.Equal(t, errAccept, err)
+	case <-time.After(time.Second):
+		t.Fatal("admin failure did not stop the server")
+	}
+	assert.Equal(t, errAccept, s.Wait())
+	assert.Equal(t, errAccept, <-s.Err())
+}
+
+// TestServerWithoutRootLogger runs in a new process, as the other tests set up the root logger.
+func TestServerWithoutRootLogger(t *testing.T) {
+	if os.Getenv("TEST_WITHOUT_ROOT_LOGGER") == "" {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestServerWithoutRootLogger$", "-test.count=1", "-test.v")
+		cmd.Env = append(os.Environ(), "TEST_WITHOUT_ROOT_LOGGER=1")
+		out, err := cmd.CombinedOutput()
+
+		require.NoError(t, err, string(out))
+		assert.Contains(t, string(out), "INFO Starting http server")
+		assert.Contains(t, string(out), "INFO Shutting down admin http server")
+		return
+	}
+
+	require.False(t, logger.Initialized())
+	s, err := New(Config{
+		Host:                      "127.0.0.1",
+		Port:                      freePort(t),
+		Admin:                     AdminConfig{Port: freePort(t)},
+		GracefulShutdownTimeoutMs: 1000,
+	}, http.HandlerFunc(handler.PingHandler))
+	require.NoError(t, err)
+
+	require.NoError(t, s.Start())
+	// Let the serve goroutines log their start
+	time.Sleep(50 * time.Millisecond)
+	assert.NoError(t, s.Shutdown())
+	assert.NoError(t, s.Wait())
+}
@@ -0,0 +1,335 @@
+package server
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	ClientAuthRequire        = "require"
+	ClientAuthVerifyIfGiven  = "verify_if_given"
+	CipherPolicyModern       = "modern"
+	CipherPolicyIntermediate = "intermediate"
+)
+
+// TLSConfig enables TLS when CertFile and KeyFile are set.
+type TLSConfig struct {
+	CertFile string `mapstructure:"cert_file"`
+	KeyFile  string `mapstructure:"key_file"`
+	// MinVersion is one of 1.0, 1.1, 1.2 (default) or 1.3
+	MinVersion string `mapstructure:"min_version"`
+	// CipherPolicy restricts TLS 1.2 cipher suites to the modern (ECDHE with AEAD) or
+	// intermediate (ECDHE) ones. Go defaults are used when empty.
+	CipherPolicy string `mapstructure:"cipher_policy"`
+	// CipherSuites lists cipher suite names, e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	// and takes precedence over CipherPolicy
+	CipherSuites []string `mapstructure:"cipher_suites"`
+	// ClientCAFile enables mutual TLS, verifying client certificates against these CAs
+	ClientCAFile string `mapstructure:"client_ca_file"`
+	// ClientAuth is either require (default) or verify_if_given
+	ClientAuth string `mapstructure:"client_auth"`
+	// ReloadIntervalMs is how often files are checked for changes. Zero disables reloading.
+	ReloadIntervalMs int `mapstructure:"reload_interval_ms"`
+}
+
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" && c.KeyFile != ""
+}
+
+// validate rejects incomplete configurations, which would otherwise silently serve
+// plain HTTP.
+func (c TLSConfig) validate() error {
+	if c.Enabled() {
+		return nil
+	}
+	configured := c.CertFile != "" || c.KeyFile != "" || c.MinVersion != "" || c.CipherPolicy != "" ||
+		len(c.CipherSuites) > 0 || c.ClientCAFile != "" || c.ClientAuth != "" || c.ReloadIntervalMs != 0
+	if configured {
+		return fmt.Errorf("incomplete tls configuration: cert_file and key_file are both required")
+	}
+	return nil
+}
+
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+var modernCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305,
+}
+
+var intermediateCipherSuites = append([]uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_CBC_SHA,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_CBC_SHA,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_CBC_SHA,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_CBC_SHA,
+}, modernCipherSuites...)
+
+func newTLSConfig(c TLSConfig, reloader *certReloader) (*tls.Config, error) {
+	minVersion := uint16(tls.VersionTLS12)
+	if c.MinVersion != "" {
+		version, ok := tlsVersions[c.MinVersion]
+		if !ok {
+			return nil, fmt.Errorf("unknown tls version %q", c.MinVersion)
+		}
+		minVersion = version
+	}
+
+	cipherSuites, err := parseCipherSuites(c)
+	if err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+		GetCertificate: reloader.getCertificate,
+		NextProtos:     []string{"h2", "http/1.1"},
+	}
+
+	if c.ClientCAFile != "" {
+		switch c.ClientAuth {
+		case "", ClientAuthRequire:
+			config.ClientAuth = tls.RequireAndVerifyClientCert
+		case ClientAuthVerifyIfGiven:
+			config.ClientAuth = tls.VerifyClientCertIfGiven
+		default:
+			return nil, fmt.Errorf("unknown tls client auth %q", c.ClientAuth)
+		}
+
+		// The client CAs are looked up on every handshake so that they can be reloaded
+		config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			clientConfig := config.Clone()
+			clientConfig.GetConfigForClient = nil
+			clientConfig.ClientCAs = reloader.clientCAs()
+			return clientConfig, nil
+		}
+	}
+
+	return config, nil
+}
+
+func parseCipherSuites(c TLSConfig) ([]uint16, error) {
+	if len(c.CipherSuites) > 0 {
+		byName := make(map[string]uint16)
+		for _, suite := range tls.CipherSuites() {
+			byName[suite.Name] = suite.ID
+		}
+
+		suites := make([]uint16, 0, len(c.CipherSuites))
+		for _, name := range c.CipherSuites {
+			id, ok := byName[strings.ToUpper(name)]
+			if !ok {
+				return nil, fmt.Errorf("unknown or insecure cipher suite %q", name)
+			}
+			suites = append(suites, id)
+		}
+		return suites, nil
+	}
+
+	switch c.CipherPolicy {
+	case "":
+		return nil, nil
+	case CipherPolicyModern:
+		return modernCipherSuites, nil
+	case CipherPolicyIntermediate:
+		return intermediateCipherSuites, nil
+	}
+	return nil, fmt.Errorf("unknown cipher policy %q", c.CipherPolicy)
+}
+
+// certReloader serves the certificate and client CAs read from disk, and reloads them
+// when the files are modified.
+type certReloader struct {
+	config TLSConfig
+
+	mu       sync.RWMutex
+	cert     *tls.Certificate
+	caPool   *x509.CertPool
+	modTimes map[string]time.Time
+
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+func newCertReloader(c TLSConfig) (*certReloader, error) {
+	r := &certReloader{
+		config: c,
+		stop:   make(chan struct{}),
+	}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) files() []string {
+	files := []string{r.config.CertFile, r.config.KeyFile}
+	if r.config.ClientCAFile != "" {
+		files = append(files, r.config.ClientCAFile)
+	}
+	return files
+}
+
+func (r *certReloader) load() error {
+	modTimes := make(map[string]time.Time)
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			return fmt.Errorf("%w :: Failed to read tls file", err)
+		}
+		modTimes[file] = info.ModTime()
+	}
+
+	cert, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("%w :: Failed to load tls certificate %s", err, r.config.CertFile)
+	}
+
+	var caPool *x509.CertPool
+	if r.config.ClientCAFile != "" {
+		pem, err := ioutil.ReadFile(r.config.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("%w :: Failed to read client CA file %s", err, r.config.ClientCAFile)
+		}
+		caPool = x509.NewCertPool()
+		if !caPool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("no valid certificate found in client CA file %s", r.config.ClientCAFile)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cert = &cert
+	r.caPool = caPool
+	r.modTimes = modTimes
+	return nil
+}
+
+func (r *certReloader) modified() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for _, file := range r.files() {
+		info, err := os.Stat(file)
+		if err != nil {
+			// Files may be briefly missing while being replaced
+			continue
+		}
+		if !info.ModTime().Equal(r.modTimes[file]) {
+			return true
+		}
+	}
+	return false
+}
+
+// start watches the files for changes in the background until close is called
+func (r *certReloader) start(interval time.Duration) {
+	r.wg.Add(1)
+	go func() {
+		defer r.wg.Done()
+		r.watch(interval)
+	}()
+}
+
+// watch polls the files for changes until close is called
+func (r *certReloader) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			if !r.modified() {
+				continue
+			}
+			if err := r.load(); err != nil {
+				serverLogger().Errorf("Failed to reload tls certificates, keeping the previous ones: %v", err)
+				continue
+			}
+			serverLogger().Infof("Reloaded tls certificates")
+		}
+	}
+}
+
+// close stops watching the files and waits for the watcher to return
+func (r *certReloader) close() {
+	r.stopOnce.Do(func() {
+		close(r.stop)
+	})
+	r.wg.Wait()
+}
+
+func (r *certReloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+func (r *certReloader) clientCAs() *x509.CertPool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.caPool
+}
+
+// PeerIdentity describes the verified client certificate of a mutual TLS connection.
+type PeerIdentity struct {
+	CommonName     string
+	Organization   []string
+	DNSNames       []string
+	EmailAddresses []string
+	URIs           []string
+	SerialNumber   string
+}
+
+type contextKey int
+
+const peerIdentityKey contextKey = iota
+
+// PeerIdentityFromContext returns the identity of the client verified with mutual TLS.
+func PeerIdentityFromContext(ctx context.Context) (*PeerIdentity, bool) {
+	identity, ok := ctx.Value(peerIdentityKey).(*PeerIdentity)
+	return identity, ok
+}
+
+func peerIdentity(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 || len(r.TLS.VerifiedChains[0]) == 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cert := r.TLS.VerifiedChains[0][0]
+		identity := &PeerIdentity{
+			CommonName:     cert.Subject.CommonName,
+			Organization:   cert.Subject.Organization,
+			DNSNames:       cert.DNSNames,
+			EmailAddresses: cert.EmailAddresses,
+			SerialNumber:   cert.SerialNumber.String(),
+		}
+		for _, uri := range cert.URIs {
+			identity.URIs = append(identity.URIs, uri.String())
+		}
+
+		ctx := context.WithValue(r.Context(), peerIdentityKey, identity)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/callicoder/go-commons/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCert struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newTestCert(t *testing.T, template *x509.Certificate, parent *testCert) *testCert {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.NotBefore = time.Now().Add(-time.Hour)
+	template.NotAfter = time.Now().Add(time.Hour)
+
+	parentCert, parentKey := template, key
+	if parent != nil {
+		parentCert, parentKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, parentCert, &key.PublicKey, parentKey)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	return &testCert{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func freePort(t *testing.T) int {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+	return lis.Addr().(*net.TCPAddr).Port
+}
+
+func TestMutualTLS(t *testing.T) {
+	logger.SetupRootLogger(logger.Config{Level: "panic"})
+
+	dir, err := ioutil.TempDir("", "tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	ca := newTestCert(t, &x509.Certificate{
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}, nil)
+	serverCert := newTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "server"},
+		IPAddresses: []net.IP{net.ParseIP("127.0.0.1")},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}, ca)
+	clientCert := newTestCert(t, &x509.Certificate{
+		Subject:     pkix.Name{CommonName: "orders-service"},
+		DNSNames:    []string{"orders.internal"},
+		ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}, ca)
+
+	files := map[string][]byte{
+		"ca.pem":   ca.certPEM,
+		"cert.pem": serverCert.certPEM,
+		"key.pem":  serverCert.keyPEM,
+	}
+	for name, content := range files {
+		require.NoError(t, ioutil.WriteFile(filepath.Join(dir, name), content, 0600))
+	}
+
+	port := freePort(t)
+	var identity *PeerIdentity
+	s, err := New(Config{
+		Port: port,
+		TLS: TLSConfig{
+			CertFile:     filepath.Join(dir, "cert.pem"),
+			KeyFile:      filepath.Join(dir, "key.pem"),
+			ClientCAFile: filepath.Join(dir, "ca.pem"),
+			CipherPolicy: CipherPolicyModern,
+		},
+	}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		identity, _ = PeerIdentityFromContext(r.Context())
+	}))
+	require.NoError(t, err)
+	require.NoError(t, s.Start())
+	defer s.Shutdown()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	url := fmt.Sprintf("https://127.0.0.1:%d/", port)
+
+	t.Run("should reject clients without a certificate", func(t *testing.T) {
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}}
+
+		_, err := client.Get(url)
+
+		assert.Error(t, err)
+	})
+
+	t.Run("should expose the verified client identity", func(t *testing.T) {
+		keyPair, err := tls.X509KeyPair(clientCert.certPEM, clientCert.keyPEM)
+		require.NoError(t, err)
+		client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{
+			RootCAs:      roots,
+			Certificates: []tls.Certificate{keyPair},
+		}}}
+
+		resp, err := client.Get(url)
+
+		require.NoError(t, err)
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+		require.NotNil(t, identity)
+		assert.Equal(t, "orders-service", identity.CommonName)
+		assert.Equal(t, []string{"orders.internal"}, identity.DNSNames)
+	})
+}
+
+func TestTLSConfig(t *testing.T) {
+	for name, conf := range map[string]TLSConfig{
+		"cert without key":       {CertFile: "cert.pem"},
+		"key without cert":       {KeyFile: "key.pem"},
+		"client CA without cert": {ClientCAFile: "ca.pem"},
+		"min version only":       {MinVersion: "1.3"},
+	} {
+		_, err := New(Config{TLS: conf}, http.NotFoundHandler())
+
+		assert.EqualError(t, err, "incomplete tls configuration: cert_file and key_file are both required", name)
+	}
+}
+
+func TestCertReloader(t *testing.T) {
+	logger.SetupRootLogger(logger.Config{Level: "panic"})
+
+	dir, err := ioutil.TempDir("", "tls")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	write := func(cert *testCert, modTime time.Time) {
+		require.NoError(t, ioutil.WriteFile(certFile, cert.certPEM, 0600))
+		require.NoError(t, ioutil.WriteFile(keyFile, cert.keyPEM, 0600))
+		require.NoError(t, os.Chtimes(certFile, modTime, modTime))
+		require.NoError(t, os.Chtimes(keyFile, modTime, modTime))
+	}
+
+	first := newTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "first"}}, nil)
+	write(first, time.Now().Add(-time.Minute))
+
+	reloader, err := newCertReloader(TLSConfig{CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+	reloader.start(10 * time.Millisecond)
+	defer reloader.close()
+
+	second := newTestCert(t, &x509.Certificate{Subject: pkix.Name{CommonName: "second"}}, nil)
+	write(second, time.Now())
+
+	assert.Eventually(t, func() bool {
+		cert, _ := reloader.getCertificate(nil)
+		leaf, err := x509.ParseCertificate(cert.Certificate[0])
+		return err == nil && leaf.Subject.CommonName == "second"
+	}, time.Second, 10*time.Millisecond)
+}
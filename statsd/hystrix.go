@@ -0,0 +1,166 @@
+package statsd
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	metricCollector "github.com/afex/hystrix-go/hystrix/metric_collector"
+	cactus "github.com/cactus/go-statsd-client/statsd"
+)
+
+// hystrixFlushBytes is the size of the statsd packets sent, fitting a LAN MTU
+const hystrixFlushBytes = 1432
+
+// hystrixRegistry forwards hystrix circuit metrics to the statsd client currently
+// registered. hystrix offers no way to remove a collector from its registry, so a single
+// forwarding collector is registered once and the statsd client behind it is swapped,
+// closing the previous one.
+type hystrixRegistry struct {
+	once       sync.Once
+	mu         sync.RWMutex
+	client     cactus.Statter
+	generation int
+}
+
+var hystrixCollectors = &hystrixRegistry{}
+
+// RegisterHystrixCollector reports the metrics of all hystrix circuits to the statsd
+// server of c, prefixed with <namespace>.hystrix. Calling it again replaces the
+// previous collector instead of registering a second one.
+func RegisterHystrixCollector(c Config) error {
+	_, err := registerHystrixCollector(c)
+	return err
+}
+
+// UnregisterHystrixCollector stops reporting hystrix circuit metrics to statsd and closes
+// the statsd client of the collector.
+func UnregisterHystrixCollector() {
+	unregisterHystrixCollector(0)
+}
+
+func registerHystrixCollector(c Config) (int, error) {
+	address := fmt.Sprintf("%s:%d", c.Host, c.Port)
+	client, err := cactus.NewBufferedClient(address, c.Namespace+".hystrix", time.Second, hystrixFlushBytes)
+	if err != nil {
+		return 0, fmt.Errorf("error initiating hystrix collector on statsD %+v", err)
+	}
+
+	hystrixCollectors.once.Do(func() {
+		metricCollector.Registry.Register(hystrixCollectors.newCollector)
+	})
+
+	hystrixCollectors.mu.Lock()
+	defer hystrixCollectors.mu.Unlock()
+	hystrixCollectors.swap(client)
+	return hystrixCollectors.generation, nil
+}
+
+// unregisterHystrixCollector removes the current collector, unless generation is set
+// and a newer collector has been registered since.
+func unregisterHystrixCollector(generation int) {
+	hystrixCollectors.mu.Lock()
+	defer hystrixCollectors.mu.Unlock()
+	if generation != 0 && generation != hystrixCollectors.generation {
+		return
+	}
+	hystrixCollectors.swap(nil)
+}
+
+// swap replaces the current statsd client by client and closes it. It must be called
+// with the lock held.
+func (h *hystrixRegistry) swap(client cactus.Statter) {
+	if h.client != nil {
+		_ = h.client.Close()
+	}
+	h.client = client
+	h.generation++
+}
+
+func (h *hystrixRegistry) newCollector(name string) metricCollector.MetricCollector {
+	return &forwardingCollector{registry: h, name: name, generation: -1}
+}
+
+type forwardingCollector struct {
+	registry   *hystrixRegistry
+	name       string
+	mu         sync.Mutex
+	generation int
+	collector  metricCollector.MetricCollector
+}
+
+func (f *forwardingCollector) current() metricCollector.MetricCollector {
+	f.registry.mu.RLock()
+	defer f.registry.mu.RUnlock()
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.generation != f.registry.generation {
+		f.generation = f.registry.generation
+		f.collector = nil
+		if f.registry.client != nil {
+			f.collector = newHystrixCollector(f.registry.client, f.name)
+		}
+	}
+	return f.collector
+}
+
+func (f *forwardingCollector) Update(r metricCollector.MetricResult) {
+	if collector := f.current(); collector != nil {
+		collector.Update(r)
+	}
+}
+
+func (f *forwardingCollector) Reset() {
+	if collector := f.current(); collector != nil {
+		collector.Reset()
+	}
+}
+
+// hystrixCollector reports the metrics of a circuit as {prefix}.{circuit}.{metric}, as
+// the statsd collector of the hystrix plugins package does, but on a client it does not own.
+type hystrixCollector struct {
+	client cactus.Statter
+	prefix string
+}
+
+func newHystrixCollector(client cactus.Statter, name string) *hystrixCollector {
+	return &hystrixCollector{
+		client: client,
+		prefix: strings.NewReplacer("/", "-", ":", "-", ".", "-").Replace(name) + ".",
+	}
+}
+
+// Update sends the metrics of a command execution. Send errors are ignored, as with the
+// UDP client they can only be local.
+func (h *hystrixCollector) Update(r metricCollector.MetricResult) {
+	if r.Successes > 0 {
+		_ = h.client.Gauge(h.prefix+"circuitOpen", 0, 1)
+	} else if r.ShortCircuits > 0 {
+		_ = h.client.Gauge(h.prefix+"circuitOpen", 1, 1)
+	}
+
+	h.increment("attempts", r.Attempts)
+	h.increment("errors", r.Errors)
+	h.increment("successes", r.Successes)
+	h.increment("failures", r.Failures)
+	h.increment("rejects", r.Rejects)
+	h.increment("shortCircuits", r.ShortCircuits)
+	h.increment("timeouts", r.Timeouts)
+	h.increment("fallbackSuccesses", r.FallbackSuccesses)
+	h.increment("fallbackFailures", r.FallbackFailures)
+	h.increment("contextCanceled", r.ContextCanceled)
+	h.increment("contextDeadlineExceeded", r.ContextDeadlineExceeded)
+	_ = h.client.TimingDuration(h.prefix+"totalDuration", r.TotalDuration, 1)
+	_ = h.client.TimingDuration(h.prefix+"runDuration", r.RunDuration, 1)
+	_ = h.client.Timing(h.prefix+"concurrencyInUse", int64(100*r.ConcurrencyInUse), 1)
+}
+
+func (h *hystrixCollector) Reset() {}
+
+func (h *hystrixCollector) increment(metric string, value float64) {
+	if value != 0 {
+		_ = h.client.Inc(h.prefix+metric, int64(value), 1)
+	}
+}
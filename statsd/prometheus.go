@@ -0,0 +1,259 @@
+package statsd
+
+import (
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DataDog/datadog-go/statsd"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	ErrUnsupportedMetric = errors.New("metric type not supported by the prometheus backend")
+	ErrNegativeCount     = errors.New("prometheus counters can not be decremented")
+)
+
+type PrometheusConfig struct {
+	// Buckets used for histograms and distributions, defaults to prometheus.DefBuckets
+	Buckets []float64
+	// TimingBuckets used for timings in seconds, defaults to prometheus.DefBuckets
+	TimingBuckets []float64 `mapstructure:"timing_buckets"`
+}
+
+// prometheusClient implements statsd.ClientInterface on top of a prometheus registry.
+// Metric names are prefixed with the namespace and sanitized, and tags of the form
+// key:value are translated to labels. A metric must always be reported with the same tag keys.
+type prometheusClient struct {
+	registry      *prometheus.Registry
+	handler       http.Handler
+	namespace     string
+	tags          []string
+	buckets       []float64
+	timingBuckets []float64
+
+	mu         sync.Mutex
+	counters   map[string]*prometheus.CounterVec
+	gauges     map[string]*prometheus.GaugeVec
+	histograms map[string]*prometheus.HistogramVec
+}
+
+func newPrometheusClient(c Config) (*prometheusClient, error) {
+	registry := prometheus.NewRegistry()
+	if err := registry.Register(prometheus.NewGoCollector()); err != nil {
+		return nil, err
+	}
+	if err := registry.Register(prometheus.NewProcessCollector(prometheus.ProcessCollectorOpts{})); err != nil {
+		return nil, err
+	}
+
+	buckets := c.Prometheus.Buckets
+	if len(buckets) == 0 {
+		buckets = prometheus.DefBuckets
+	}
+	timingBuckets := c.Prometheus.TimingBuckets
+	if len(timingBuckets) == 0 {
+		timingBuckets = prometheus.DefBuckets
+	}
+
+	return &prometheusClient{
+		registry:      registry,
+		handler:       promhttp.HandlerFor(registry, promhttp.HandlerOpts{}),
+		namespace:     c.Namespace,
+		tags:          c.Tags,
+		buckets:       buckets,
+		timingBuckets: timingBuckets,
+		counters:      make(map[string]*prometheus.CounterVec),
+		gauges:        make(map[string]*prometheus.GaugeVec),
+		histograms:    make(map[string]*prometheus.HistogramVec),
+	}, nil
+}
+
+// Handler serves the metrics of the registry in the prometheus exposition format.
+func (p *prometheusClient) Handler() http.Handler {
+	return p.handler
+}
+
+func (p *prometheusClient) Gauge(name string, value float64, tags []string, rate float64) error {
+	labels := p.labels(tags)
+	name = p.metricName(name, "")
+
+	p.mu.Lock()
+	vec, ok := p.gauges[name]
+	if !ok {
+		vec = prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: name, Help: name}, labelNames(labels))
+		if err := p.registry.Register(vec); err != nil {
+			p.mu.Unlock()
+			return err
+		}
+		p.gauges[name] = vec
+	}
+	p.mu.Unlock()
+
+	gauge, err := vec.GetMetricWith(labels)
+	if err != nil {
+		return err
+	}
+	gauge.Set(value)
+	return nil
+}
+
+func (p *prometheusClient) Count(name string, value int64, tags []string, rate float64) error {
+	if value < 0 {
+		return ErrNegativeCount
+	}
+
+	labels := p.labels(tags)
+	name = p.metricName(name, "_total")
+
+	p.mu.Lock()
+	vec, ok := p.counters[name]
+	if !ok {
+		vec = prometheus.NewCounterVec(prometheus.CounterOpts{Name: name, Help: name}, labelNames(labels))
+		if err := p.registry.Register(vec); err != nil {
+			p.mu.Unlock()
+			return err
+		}
+		p.counters[name] = vec
+	}
+	p.mu.Unlock()
+
+	counter, err := vec.GetMetricWith(labels)
+	if err != nil {
+		return err
+	}
+	counter.Add(float64(value))
+	return nil
+}
+
+func (p *prometheusClient) Histogram(name string, value float64, tags []string, rate float64) error {
+	return p.observe(p.metricName(name, ""), p.buckets, value, tags)
+}
+
+func (p *prometheusClient) Distribution(name string, value float64, tags []string, rate float64) error {
+	return p.observe(p.metricName(name, ""), p.buckets, value, tags)
+}
+
+func (p *prometheusClient) Decr(name string, tags []string, rate float64) error {
+	return p.Count(name, -1, tags, rate)
+}
+
+func (p *prometheusClient) Incr(name string, tags []string, rate float64) error {
+	return p.Count(name, 1, tags, rate)
+}
+
+func (p *prometheusClient) Set(name string, value string, tags []string, rate float64) error {
+	return ErrUnsupportedMetric
+}
+
+func (p *prometheusClient) Timing(name string, value time.Duration, tags []string, rate float64) error {
+	return p.observe(p.metricName(name, "_seconds"), p.timingBuckets, value.Seconds(), tags)
+}
+
+func (p *prometheusClient) TimeInMilliseconds(name string, value float64, tags []string, rate float64) error {
+	return p.observe(p.metricName(name, "_seconds"), p.timingBuckets, value/1000, tags)
+}
+
+// Events and service checks have no prometheus equivalent and are dropped
+func (p *prometheusClient) Event(e *statsd.Event) error {
+	return nil
+}
+
+func (p *prometheusClient) SimpleEvent(title, text string) error {
+	return nil
+}
+
+func (p *prometheusClient) ServiceCheck(sc *statsd.ServiceCheck) error {
+	return nil
+}
+
+func (p *prometheusClient) SimpleServiceCheck(name string, status statsd.ServiceCheckStatus) error {
+	return nil
+}
+
+func (p *prometheusClient) Close() error {
+	return nil
+}
+
+func (p *prometheusClient) Flush() error {
+	return nil
+}
+
+func (p *prometheusClient) SetWriteTimeout(d time.Duration) error {
+	return nil
+}
+
+func (p *prometheusClient) observe(name string, buckets []float64, value float64, tags []string) error {
+	labels := p.labels(tags)
+
+	p.mu.Lock()
+	vec, ok := p.histograms[name]
+	if !ok {
+		vec = prometheus.NewHistogramVec(prometheus.HistogramOpts{Name: name, Help: name, Buckets: buckets}, labelNames(labels))
+		if err := p.registry.Register(vec); err != nil {
+			p.mu.Unlock()
+			return err
+		}
+		p.histograms[name] = vec
+	}
+	p.mu.Unlock()
+
+	observer, err := vec.GetMetricWith(labels)
+	if err != nil {
+		return err
+	}
+	observer.Observe(value)
+	return nil
+}
+
+// metricName translates a statsd metric name such as http.server.latency to
+// a prometheus one such as namespace_http_server_latency
+func (p *prometheusClient) metricName(name, suffix string) string {
+	if p.namespace != "" {
+		name = p.namespace + "." + name
+	}
+	name = sanitize(name)
+	if !strings.HasSuffix(name, suffix) {
+		name += suffix
+	}
+	return name
+}
+
+// labels translates statsd tags of the form key:value to prometheus labels.
+// Tags without a value are translated to a label with the value "true".
+func (p *prometheusClient) labels(tags []string) prometheus.Labels {
+	labels := prometheus.Labels{}
+	for _, list := range [][]string{p.tags, tags} {
+		for _, tag := range list {
+			key, value := tag, "true"
+			if i := strings.IndexByte(tag, ':'); i >= 0 {
+				key, value = tag[:i], tag[i+1:]
+			}
+			labels[sanitize(key)] = value
+		}
+	}
+	return labels
+}
+
+func labelNames(labels prometheus.Labels) []string {
+	names := make([]string, 0, len(labels))
+	for name := range labels {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func sanitize(name string) string {
+	b := []byte(name)
+	for i, c := range b {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || c >= '0' && c <= '9' && i > 0) {
+			b[i] = '_'
+		}
+	}
+	return string(b)
+}
@@ -2,10 +2,16 @@ package statsd
 
 import (
 	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
 
 	"github.com/DataDog/datadog-go/statsd"
-	metricCollector "github.com/afex/hystrix-go/hystrix/metric_collector"
-	"github.com/afex/hystrix-go/plugins"
+)
+
+const (
+	BackendStatsd     = "statsd"
+	BackendPrometheus = "prometheus"
 )
 
 type Config struct {
@@ -14,6 +20,14 @@ type Config struct {
 	Tags      []string
 	Namespace string
 	Enabled   bool
+	// Backend is either statsd (default) or prometheus. Prometheus counters can only go
+	// up, so Decrement, DecrementWithTags and DecrementBy return ErrNegativeCount with
+	// the prometheus backend, use a gauge instead. Set returns ErrUnsupportedMetric.
+	Backend    string
+	Prometheus PrometheusConfig
+	// Hystrix enables reporting of hystrix circuit metrics, see RegisterHystrixCollector.
+	// Only supported by the statsd backend, NewClient fails with the prometheus backend.
+	Hystrix bool
 }
 
 type Client interface {
@@ -23,11 +37,26 @@ type Client interface {
 	DecrementWithTags(name string, tags ...string) error
 	IncrementBy(name string, value float64) error
 	DecrementBy(name string, value float64) error
+	Gauge(name string, value float64) error
+	GaugeWithTags(name string, value float64, tags ...string) error
+	Timing(name string, value time.Duration) error
+	TimingWithTags(name string, value time.Duration, tags ...string) error
+	Histogram(name string, value float64) error
+	HistogramWithTags(name string, value float64, tags ...string) error
+	Distribution(name string, value float64) error
+	DistributionWithTags(name string, value float64, tags ...string) error
+	Set(name string, value string) error
+	SetWithTags(name string, value string, tags ...string) error
+	StartTimer(name string, tags ...string) *Timer
 	Close() error
 }
 
 type Reporter struct {
 	Client statsd.ClientInterface
+	// hystrixGeneration identifies the hystrix collector registered by this reporter, if any
+	hystrixGeneration int
+	// closed is 1 once Close was called
+	closed int32
 }
 
 func NewClient(c Config) (*Reporter, error) {
@@ -37,6 +66,24 @@ func NewClient(c Config) (*Reporter, error) {
 		return &Reporter{Client: client}, nil
 	}
 
+	switch c.Backend {
+	case "", BackendStatsd:
+		return newStatsdReporter(c)
+	case BackendPrometheus:
+		if c.Hystrix {
+			return nil, fmt.Errorf("hystrix metrics are not supported by the %s metrics backend", c.Backend)
+		}
+		client, err := newPrometheusClient(c)
+		if err != nil {
+			return nil, err
+		}
+		return &Reporter{Client: client}, nil
+	}
+
+	return nil, fmt.Errorf("unknown metrics backend %q", c.Backend)
+}
+
+func newStatsdReporter(c Config) (*Reporter, error) {
 	address := fmt.Sprintf("%s:%d", c.Host, c.Port)
 	client, err := statsd.New(address)
 	if err != nil {
@@ -45,17 +92,17 @@ func NewClient(c Config) (*Reporter, error) {
 	client.Namespace = c.Namespace
 	client.Tags = c.Tags
 
-	collector, err := plugins.InitializeStatsdCollector(&plugins.StatsdCollectorConfig{
-		StatsdAddr: address,
-		Prefix:     c.Namespace + ".hystrix",
-	})
-	if err != nil {
-		client.Close()
-		return nil, fmt.Errorf("error initiating hystrix collector on statsD %+v", err)
+	reporter := &Reporter{Client: client}
+	if c.Hystrix {
+		generation, err := registerHystrixCollector(c)
+		if err != nil {
+			client.Close()
+			return nil, err
+		}
+		reporter.hystrixGeneration = generation
 	}
-	metricCollector.Registry.Register(collector.NewStatsdCollector)
 
-	return &Reporter{Client: client}, nil
+	return reporter, nil
 }
 
 func (r *Reporter) Increment(name string) error {
@@ -74,14 +121,80 @@ func (r *Reporter) DecrementWithTags(name string, tags ...string) error {
 	return r.Client.Decr(name, tags, 1)
 }
 
+// IncrementBy adds value to the counter name. Counters are integers, value is sent as a
+// count truncated towards zero, e.g. 2.7 adds 2.
 func (r *Reporter) IncrementBy(name string, value float64) error {
-	return r.Client.Incr(name, nil, value)
+	return r.Client.Count(name, int64(value), nil, 1)
 }
 
+// DecrementBy subtracts value from the counter name. Counters are integers, value is sent
+// as a negative count truncated towards zero, e.g. 2.7 subtracts 2.
 func (r *Reporter) DecrementBy(name string, value float64) error {
-	return r.Client.Decr(name, nil, value)
+	return r.Client.Count(name, -int64(value), nil, 1)
+}
+
+func (r *Reporter) Gauge(name string, value float64) error {
+	return r.Client.Gauge(name, value, nil, 1)
+}
+
+func (r *Reporter) GaugeWithTags(name string, value float64, tags ...string) error {
+	return r.Client.Gauge(name, value, tags, 1)
+}
+
+func (r *Reporter) Timing(name string, value time.Duration) error {
+	return r.Client.Timing(name, value, nil, 1)
+}
+
+func (r *Reporter) TimingWithTags(name string, value time.Duration, tags ...string) error {
+	return r.Client.Timing(name, value, tags, 1)
+}
+
+func (r *Reporter) Histogram(name string, value float64) error {
+	return r.Client.Histogram(name, value, nil, 1)
+}
+
+func (r *Reporter) HistogramWithTags(name string, value float64, tags ...string) error {
+	return r.Client.Histogram(name, value, tags, 1)
+}
+
+func (r *Reporter) Distribution(name string, value float64) error {
+	return r.Client.Distribution(name, value, nil, 1)
+}
+
+func (r *Reporter) DistributionWithTags(name string, value float64, tags ...string) error {
+	return r.Client.Distribution(name, value, tags, 1)
+}
+
+func (r *Reporter) Set(name string, value string) error {
+	return r.Client.Set(name, value, nil, 1)
+}
+
+func (r *Reporter) SetWithTags(name string, value string, tags ...string) error {
+	return r.Client.Set(name, value, tags, 1)
+}
+
+func (r *Reporter) StartTimer(name string, tags ...string) *Timer {
+	return NewTimer(r, name, tags...)
+}
+
+// Handler returns the http handler exposing the metrics when the backend is scraped,
+// as with prometheus, or nil when metrics are pushed.
+func (r *Reporter) Handler() http.Handler {
+	if h, ok := r.Client.(interface{ Handler() http.Handler }); ok {
+		return h.Handler()
+	}
+	return nil
 }
 
 func (r *Reporter) Close() error {
+	atomic.StoreInt32(&r.closed, 1)
+	if r.hystrixGeneration != 0 {
+		unregisterHystrixCollector(r.hystrixGeneration)
+	}
 	return r.Client.Close()
 }
+
+// Closed reports whether Close was called.
+func (r *Reporter) Closed() bool {
+	return atomic.LoadInt32(&r.closed) == 1
+}
@@ -1,10 +1,18 @@
 package statsd
 
 import (
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/DataDog/datadog-go/statsd"
+	metricCollector "github.com/afex/hystrix-go/hystrix/metric_collector"
+	cactus "github.com/cactus/go-statsd-client/statsd"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestReporter(t *testing.T) {
@@ -38,4 +46,224 @@ func TestReporter(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, &statsd.NoOpClient{}, reporter.Client.(*statsd.NoOpClient))
 	})
+	t.Run("should expose metrics through the prometheus backend", func(t *testing.T) {
+		var testConfig = Config{
+			Tags:      []string{"env:test"},
+			Namespace: "test",
+			Enabled:   true,
+			Backend:   BackendPrometheus,
+		}
+
+		reporter, err := NewClient(testConfig)
+		assert.NoError(t, err)
+
+		assert.NoError(t, reporter.IncrementWithTags("http.requests", "method:GET"))
+		assert.NoError(t, reporter.GaugeWithTags("queue.size", 3, "queue:emails"))
+		assert.NoError(t, reporter.TimingWithTags("http.latency", 20*time.Millisecond, "method:GET"))
+		assert.Equal(t, ErrNegativeCount, reporter.Decrement("http.requests"))
+		assert.Equal(t, ErrNegativeCount, reporter.DecrementWithTags("http.requests", "method:GET"))
+		assert.Equal(t, ErrNegativeCount, reporter.DecrementBy("http.requests", 2))
+		assert.Equal(t, ErrUnsupportedMetric, reporter.Set("users.active", "sachin"))
+
+		rec := httptest.NewRecorder()
+		reporter.Handler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+
+		body := rec.Body.String()
+		assert.Contains(t, body, `test_http_requests_total{env="test",method="GET"} 1`)
+		assert.Contains(t, body, `test_queue_size{env="test",queue="emails"} 3`)
+		assert.Contains(t, body, `test_http_latency_seconds_count{env="test",method="GET"} 1`)
+	})
+
+	t.Run("should reject hystrix metrics with the prometheus backend", func(t *testing.T) {
+		var testConfig = Config{
+			Enabled: true,
+			Backend: BackendPrometheus,
+			Hystrix: true,
+		}
+
+		reporter, err := NewClient(testConfig)
+
+		assert.EqualError(t, err, "hystrix metrics are not supported by the prometheus metrics backend")
+		assert.Nil(t, reporter)
+	})
+}
+
+type call struct {
+	method string
+	name   string
+	value  interface{}
+	tags   []string
+}
+
+// recordingClient is a statsd.ClientInterface recording the calls made by the reporter
+type recordingClient struct {
+	*statsd.NoOpClient
+	calls []call
+}
+
+func (c *recordingClient) record(method, name string, value interface{}, tags []string) error {
+	c.calls = append(c.calls, call{method: method, name: name, value: value, tags: tags})
+	return nil
+}
+
+func (c *recordingClient) Gauge(name string, value float64, tags []string, rate float64) error {
+	return c.record("gauge", name, value, tags)
+}
+
+func (c *recordingClient) Count(name string, value int64, tags []string, rate float64) error {
+	return c.record("count", name, value, tags)
+}
+
+func (c *recordingClient) Histogram(name string, value float64, tags []string, rate float64) error {
+	return c.record("histogram", name, value, tags)
+}
+
+func (c *recordingClient) Distribution(name string, value float64, tags []string, rate float64) error {
+	return c.record("distribution", name, value, tags)
+}
+
+func (c *recordingClient) Set(name string, value string, tags []string, rate float64) error {
+	return c.record("set", name, value, tags)
+}
+
+func (c *recordingClient) Timing(name string, value time.Duration, tags []string, rate float64) error {
+	return c.record("timing", name, value, tags)
+}
+
+func TestReporterMetrics(t *testing.T) {
+	client := &recordingClient{NoOpClient: &statsd.NoOpClient{}}
+	reporter := &Reporter{Client: client}
+	tags := []string{"env:test"}
+
+	assert.NoError(t, reporter.Gauge("queue.size", 3))
+	assert.NoError(t, reporter.GaugeWithTags("queue.size", 4, tags...))
+	assert.NoError(t, reporter.Timing("db.query", time.Second))
+	assert.NoError(t, reporter.TimingWithTags("db.query", 2*time.Second, tags...))
+	assert.NoError(t, reporter.Histogram("payload.size", 10))
+	assert.NoError(t, reporter.HistogramWithTags("payload.size", 20, tags...))
+	assert.NoError(t, reporter.Distribution("order.amount", 5))
+	assert.NoError(t, reporter.DistributionWithTags("order.amount", 6, tags...))
+	assert.NoError(t, reporter.Set("users.active", "sachin"))
+	assert.NoError(t, reporter.SetWithTags("users.active", "rajeev", tags...))
+
+	assert.Equal(t, []call{
+		{"gauge", "queue.size", 3.0, nil},
+		{"gauge", "queue.size", 4.0, tags},
+		{"timing", "db.query", time.Second, nil},
+		{"timing", "db.query", 2 * time.Second, tags},
+		{"histogram", "payload.size", 10.0, nil},
+		{"histogram", "payload.size", 20.0, tags},
+		{"distribution", "order.amount", 5.0, nil},
+		{"distribution", "order.amount", 6.0, tags},
+		{"set", "users.active", "sachin", nil},
+		{"set", "users.active", "rajeev", tags},
+	}, client.calls)
+}
+
+func TestReporterCounters(t *testing.T) {
+	client := &recordingClient{NoOpClient: &statsd.NoOpClient{}}
+	reporter := &Reporter{Client: client}
+
+	assert.NoError(t, reporter.IncrementBy("jobs.processed", 5))
+	assert.NoError(t, reporter.DecrementBy("jobs.pending", 2.7))
+
+	assert.Equal(t, []call{
+		{"count", "jobs.processed", int64(5), nil},
+		{"count", "jobs.pending", int64(-2), nil},
+	}, client.calls)
+}
+
+func TestReporterCountersOnTheWire(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer conn.Close()
+	reporter, err := NewClient(Config{Host: "127.0.0.1", Port: conn.LocalAddr().(*net.UDPAddr).Port, Enabled: true})
+	require.NoError(t, err)
+
+	assert.NoError(t, reporter.IncrementBy("jobs.processed", 5))
+	assert.NoError(t, reporter.DecrementBy("jobs.pending", 2.7))
+	assert.NoError(t, reporter.Close())
+
+	var lines []string
+	buf := make([]byte, 1024)
+	require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+	for len(lines) < 2 {
+		n, _, err := conn.ReadFrom(buf)
+		require.NoError(t, err)
+		lines = append(lines, strings.Split(strings.TrimSpace(string(buf[:n])), "\n")...)
+	}
+	assert.Equal(t, []string{"jobs.processed:5|c", "jobs.pending:-2|c"}, lines)
+}
+
+func TestTimer(t *testing.T) {
+	client := &recordingClient{NoOpClient: &statsd.NoOpClient{}}
+	reporter := &Reporter{Client: client}
+
+	timer := reporter.StartTimer("db.query", "table:users")
+	time.Sleep(10 * time.Millisecond)
+	assert.NoError(t, timer.Stop())
+
+	assert.Len(t, client.calls, 1)
+	assert.Equal(t, "timing", client.calls[0].method)
+	assert.Equal(t, "db.query", client.calls[0].name)
+	assert.Equal(t, []string{"table:users"}, client.calls[0].tags)
+	assert.True(t, client.calls[0].value.(time.Duration) >= 10*time.Millisecond)
+	assert.True(t, timer.Elapsed() >= client.calls[0].value.(time.Duration))
+}
+
+// closingStatter records whether it was closed
+type closingStatter struct {
+	cactus.Statter
+	closed bool
+}
+
+func (c *closingStatter) Close() error {
+	c.closed = true
+	return nil
+}
+
+func TestHystrixCollector(t *testing.T) {
+	var testConfig = Config{
+		Host:      "localhost",
+		Port:      6359,
+		Namespace: "test",
+		Enabled:   true,
+		Hystrix:   true,
+	}
+
+	t.Run("should register the collector once", func(t *testing.T) {
+		first, err := NewClient(testConfig)
+		assert.NoError(t, err)
+		collectors := len(metricCollector.Registry.InitializeMetricCollectors("test"))
+
+		second, err := NewClient(testConfig)
+		assert.NoError(t, err)
+
+		assert.Len(t, metricCollector.Registry.InitializeMetricCollectors("test"), collectors)
+		assert.Equal(t, hystrixCollectors.generation, second.hystrixGeneration)
+		assert.NotEqual(t, first.hystrixGeneration, second.hystrixGeneration)
+
+		// Closing a stale reporter must not remove the collector of a newer one
+		assert.NoError(t, first.Close())
+		assert.NotNil(t, hystrixCollectors.client)
+		assert.Equal(t, second.hystrixGeneration, hystrixCollectors.generation)
+
+		assert.NoError(t, second.Close())
+		assert.Nil(t, hystrixCollectors.client)
+	})
+
+	t.Run("should close the statsd client of replaced collectors", func(t *testing.T) {
+		noop, err := cactus.NewNoopClient()
+		assert.NoError(t, err)
+		client := &closingStatter{Statter: noop}
+		hystrixCollectors.mu.Lock()
+		hystrixCollectors.swap(client)
+		hystrixCollectors.mu.Unlock()
+
+		assert.NoError(t, RegisterHystrixCollector(testConfig))
+		assert.True(t, client.closed)
+
+		UnregisterHystrixCollector()
+		assert.Nil(t, hystrixCollectors.client)
+	})
 }
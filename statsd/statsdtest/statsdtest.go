@@ -0,0 +1,229 @@
+// Package statsdtest provides a statsd.Client recording every emitted metric,
+// to assert on instrumentation in tests.
+package statsdtest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/callicoder/go-commons/sliceutil"
+	"github.com/callicoder/go-commons/statsd"
+)
+
+type MetricType string
+
+const (
+	Counter      MetricType = "counter"
+	Gauge        MetricType = "gauge"
+	Timing       MetricType = "timing"
+	Histogram    MetricType = "histogram"
+	Distribution MetricType = "distribution"
+	Set          MetricType = "set"
+)
+
+type Metric struct {
+	Type  MetricType
+	Name  string
+	Value float64
+	// SetValue is only populated for set metrics
+	SetValue string
+	Tags     []string
+}
+
+// TestingT is the subset of testing.TB used by the assertion helpers.
+type TestingT interface {
+	Errorf(format string, args ...interface{})
+}
+
+// Client is a statsd.Client which records metrics in memory. It is safe for concurrent use.
+type Client struct {
+	mu      sync.Mutex
+	metrics []Metric
+	closed  bool
+}
+
+var _ statsd.Client = (*Client)(nil)
+
+func NewClient() *Client {
+	return &Client{}
+}
+
+func (c *Client) Increment(name string) error {
+	return c.record(Counter, name, 1, "", nil)
+}
+
+func (c *Client) Decrement(name string) error {
+	return c.record(Counter, name, -1, "", nil)
+}
+
+func (c *Client) IncrementWithTags(name string, tags ...string) error {
+	return c.record(Counter, name, 1, "", tags)
+}
+
+func (c *Client) DecrementWithTags(name string, tags ...string) error {
+	return c.record(Counter, name, -1, "", tags)
+}
+
+// IncrementBy records value truncated to an integer, as statsd counters are.
+func (c *Client) IncrementBy(name string, value float64) error {
+	return c.record(Counter, name, float64(int64(value)), "", nil)
+}
+
+// DecrementBy records value truncated to an integer, as statsd counters are.
+func (c *Client) DecrementBy(name string, value float64) error {
+	return c.record(Counter, name, -float64(int64(value)), "", nil)
+}
+
+func (c *Client) Gauge(name string, value float64) error {
+	return c.record(Gauge, name, value, "", nil)
+}
+
+func (c *Client) GaugeWithTags(name string, value float64, tags ...string) error {
+	return c.record(Gauge, name, value, "", tags)
+}
+
+func (c *Client) Timing(name string, value time.Duration) error {
+	return c.record(Timing, name, float64(value), "", nil)
+}
+
+func (c *Client) TimingWithTags(name string, value time.Duration, tags ...string) error {
+	return c.record(Timing, name, float64(value), "", tags)
+}
+
+func (c *Client) Histogram(name string, value float64) error {
+	return c.record(Histogram, name, value, "", nil)
+}
+
+func (c *Client) HistogramWithTags(name string, value float64, tags ...string) error {
+	return c.record(Histogram, name, value, "", tags)
+}
+
+func (c *Client) Distribution(name string, value float64) error {
+	return c.record(Distribution, name, value, "", nil)
+}
+
+func (c *Client) DistributionWithTags(name string, value float64, tags ...string) error {
+	return c.record(Distribution, name, value, "", tags)
+}
+
+func (c *Client) Set(name string, value string) error {
+	return c.record(Set, name, 0, value, nil)
+}
+
+func (c *Client) SetWithTags(name string, value string, tags ...string) error {
+	return c.record(Set, name, 0, value, tags)
+}
+
+func (c *Client) StartTimer(name string, tags ...string) *statsd.Timer {
+	return statsd.NewTimer(c, name, tags...)
+}
+
+func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	return nil
+}
+
+func (c *Client) Closed() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.closed
+}
+
+// Metrics returns a copy of all the recorded metrics, in emission order.
+func (c *Client) Metrics() []Metric {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	metrics := make([]Metric, len(c.metrics))
+	copy(metrics, c.metrics)
+	return metrics
+}
+
+// Find returns the recorded metrics with the given name.
+func (c *Client) Find(name string) []Metric {
+	var metrics []Metric
+	for _, m := range c.Metrics() {
+		if m.Name == name {
+			metrics = append(metrics, m)
+		}
+	}
+	return metrics
+}
+
+// Reset discards all the recorded metrics.
+func (c *Client) Reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.metrics = nil
+	c.closed = false
+}
+
+// AssertCounter checks that the counter name sums up to value across all its emissions.
+func (c *Client) AssertCounter(t TestingT, name string, value float64) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+
+	found := false
+	total := 0.0
+	for _, m := range c.Find(name) {
+		if m.Type == Counter {
+			found = true
+			total += m.Value
+		}
+	}
+
+	if !found {
+		t.Errorf("counter %q was not emitted", name)
+		return false
+	}
+	if total != value {
+		t.Errorf("counter %q: expected %v, got %v", name, value, total)
+		return false
+	}
+	return true
+}
+
+// AssertTagged checks that the metric name was emitted at least once with all the given tags.
+func (c *Client) AssertTagged(t TestingT, name string, tags ...string) bool {
+	if h, ok := t.(interface{ Helper() }); ok {
+		h.Helper()
+	}
+
+	metrics := c.Find(name)
+	if len(metrics) == 0 {
+		t.Errorf("metric %q was not emitted", name)
+		return false
+	}
+
+	for _, m := range metrics {
+		if hasTags(m.Tags, tags) {
+			return true
+		}
+	}
+	t.Errorf("metric %q was not emitted with tags %v, got %v", name, tags, metrics)
+	return false
+}
+
+func (c *Client) record(metricType MetricType, name string, value float64, setValue string, tags []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.metrics = append(c.metrics, Metric{
+		Type:     metricType,
+		Name:     name,
+		Value:    value,
+		SetValue: setValue,
+		Tags:     append([]string(nil), tags...),
+	})
+	return nil
+}
+
+func hasTags(tags []string, expected []string) bool {
+	for _, tag := range expected {
+		if !sliceutil.Contains(tags, tag) {
+			return false
+		}
+	}
+	return true
+}
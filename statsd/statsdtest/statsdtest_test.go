@@ -0,0 +1,108 @@
+package statsdtest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeT records the failures reported by the assertion helpers
+type fakeT struct {
+	errors []string
+}
+
+func (f *fakeT) Errorf(format string, args ...interface{}) {
+	f.errors = append(f.errors, fmt.Sprintf(format, args...))
+}
+
+func TestClient(t *testing.T) {
+	t.Run("should record every metric", func(t *testing.T) {
+		c := NewClient()
+
+		assert.NoError(t, c.Increment("requests"))
+		assert.NoError(t, c.DecrementWithTags("requests", "method:GET"))
+		assert.NoError(t, c.IncrementBy("jobs", 2.5))
+		assert.NoError(t, c.GaugeWithTags("queue.size", 3, "queue:emails"))
+		assert.NoError(t, c.Timing("latency", time.Second))
+		assert.NoError(t, c.Set("users", "sachin"))
+
+		assert.Equal(t, []Metric{
+			{Type: Counter, Name: "requests", Value: 1},
+			{Type: Counter, Name: "requests", Value: -1, Tags: []string{"method:GET"}},
+			{Type: Counter, Name: "jobs", Value: 2},
+			{Type: Gauge, Name: "queue.size", Value: 3, Tags: []string{"queue:emails"}},
+			{Type: Timing, Name: "latency", Value: float64(time.Second)},
+			{Type: Set, Name: "users", SetValue: "sachin"},
+		}, c.Metrics())
+		assert.Len(t, c.Find("requests"), 2)
+	})
+
+	t.Run("should reset metrics and close state", func(t *testing.T) {
+		c := NewClient()
+		assert.NoError(t, c.Increment("requests"))
+		assert.NoError(t, c.Close())
+		assert.True(t, c.Closed())
+
+		c.Reset()
+
+		assert.Empty(t, c.Metrics())
+		assert.False(t, c.Closed())
+	})
+}
+
+func TestAssertCounter(t *testing.T) {
+	c := NewClient()
+	assert.NoError(t, c.Increment("requests"))
+	assert.NoError(t, c.IncrementBy("requests", 2))
+	assert.NoError(t, c.Gauge("queue.size", 3))
+
+	t.Run("should pass when the counter sums up to the value", func(t *testing.T) {
+		ft := &fakeT{}
+
+		assert.True(t, c.AssertCounter(ft, "requests", 3))
+		assert.Empty(t, ft.errors)
+	})
+
+	t.Run("should fail on a different total", func(t *testing.T) {
+		ft := &fakeT{}
+
+		assert.False(t, c.AssertCounter(ft, "requests", 1))
+		assert.Equal(t, []string{`counter "requests": expected 1, got 3`}, ft.errors)
+	})
+
+	t.Run("should fail when the counter was not emitted", func(t *testing.T) {
+		ft := &fakeT{}
+
+		assert.False(t, c.AssertCounter(ft, "queue.size", 3))
+		assert.Equal(t, []string{`counter "queue.size" was not emitted`}, ft.errors)
+	})
+}
+
+func TestAssertTagged(t *testing.T) {
+	c := NewClient()
+	assert.NoError(t, c.IncrementWithTags("requests", "method:GET", "status:200"))
+
+	t.Run("should pass when emitted with a superset of the tags", func(t *testing.T) {
+		ft := &fakeT{}
+
+		assert.True(t, c.AssertTagged(ft, "requests", "status:200"))
+		assert.Empty(t, ft.errors)
+	})
+
+	t.Run("should fail when emitted without the tags", func(t *testing.T) {
+		ft := &fakeT{}
+
+		assert.False(t, c.AssertTagged(ft, "requests", "status:500"))
+		assert.Len(t, ft.errors, 1)
+		assert.Contains(t, ft.errors[0], `metric "requests" was not emitted with tags [status:500]`)
+	})
+
+	t.Run("should fail when the metric was not emitted", func(t *testing.T) {
+		ft := &fakeT{}
+
+		assert.False(t, c.AssertTagged(ft, "responses", "status:200"))
+		assert.Equal(t, []string{`metric "responses" was not emitted`}, ft.errors)
+	})
+}
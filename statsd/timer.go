@@ -0,0 +1,33 @@
+package statsd
+
+import (
+	"time"
+)
+
+// Timer measures the time elapsed between its creation and Stop, e.g.
+//
+//	defer client.StartTimer("db.query", "table:users").Stop()
+type Timer struct {
+	client Client
+	name   string
+	tags   []string
+	start  time.Time
+}
+
+func NewTimer(client Client, name string, tags ...string) *Timer {
+	return &Timer{
+		client: client,
+		name:   name,
+		tags:   tags,
+		start:  time.Now(),
+	}
+}
+
+// Stop reports the time elapsed since the timer was started as a timing metric.
+func (t *Timer) Stop() error {
+	return t.client.TimingWithTags(t.name, t.Elapsed(), t.tags...)
+}
+
+func (t *Timer) Elapsed() time.Duration {
+	return time.Since(t.start)
+}